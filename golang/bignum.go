@@ -0,0 +1,198 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// 实现任意精度整数及十进制小数的编码
+package golang
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+)
+
+var bigIntType = reflect.TypeOf(big.Int{})
+
+// 编码为最短的大端二进制补码，与ASN.1 INTEGER相同，0编码为一个0x00字节
+func encodeBigInt(value *big.Int) []byte {
+	switch value.Sign() {
+	case 0:
+		return []byte{0}
+	case 1:
+		valueBytes := value.Bytes()
+		if valueBytes[0]&0x80 != 0 {
+			valueBytes = append([]byte{0}, valueBytes...)
+		}
+		return valueBytes
+	}
+
+	//负数的补码等于|value|-1按位取反
+	valueBytes := new(big.Int).Sub(new(big.Int).Neg(value), big.NewInt(1)).Bytes()
+	for i := range valueBytes {
+		valueBytes[i] = ^valueBytes[i]
+	}
+	if len(valueBytes) == 0 || valueBytes[0]&0x80 == 0 {
+		valueBytes = append([]byte{0xff}, valueBytes...)
+	}
+	return valueBytes
+}
+
+// 将大端二进制补码解码为整数
+func (this *TLVPkg) AsBigInt() (*big.Int, error) {
+	value := this.Value
+	if len(value) == 0 {
+		return nil, fmt.Errorf("%w: 整数不能为0字节", ErrTypeMismatch)
+	}
+
+	ret := new(big.Int).SetBytes(value)
+	if value[0]&0x80 != 0 {
+		//减去2^(8*len)得到负数
+		ret.Sub(ret, new(big.Int).Lsh(big.NewInt(1), uint(len(value))*8))
+	}
+	return ret, nil
+}
+
+// 写入任意精度整数，编码为最短的大端二进制补码
+func (this *TLVObject) PutBigInt(key int, value *big.Int) error {
+	if value == nil {
+		return ErrInvalidParam
+	}
+
+	return this.addPrimitiveNode(key, encodeBigInt(value))
+}
+
+func (this *TLVObject) GetBigInt(key int) (*big.Int, bool) {
+	ret, err := this.GetBigIntE(key)
+	return ret, err == nil
+}
+
+func (this *TLVObject) GetBigIntE(key int) (*big.Int, error) {
+	pkg, err := this.getPkgE(key)
+	if err != nil {
+		return nil, err
+	}
+	ret, err := pkg.AsBigInt()
+	return ret, keyError(err, key)
+}
+
+// 十进制小数，数值为Unscaled * 10^-Scale，如Unscaled为12345、Scale为2时表示123.45
+// 编码为TLV嵌套结构，tag 0为PutBigInt编码的Unscaled，tag 1为4字节的Scale
+type Decimal struct {
+	Unscaled *big.Int // 为nil时表示0
+	Scale    int32
+}
+
+// Decimal子节点的tag
+const (
+	decimalUnscaledTag = 0
+	decimalScaleTag    = 1
+)
+
+// 解析形如"-123.45"的十进制小数，Scale为小数点后的位数
+func ParseDecimal(s string) (Decimal, error) {
+	intPart, fracPart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+	}
+
+	unscaled, ok := new(big.Int).SetString(intPart+fracPart, 10)
+	if ok == false || strings.ContainsAny(fracPart, "+-") || (intPart == "" && fracPart == "") {
+		return Decimal{}, fmt.Errorf("%w: 无效的十进制小数%q", ErrInvalidParam, s)
+	}
+	return Decimal{Unscaled: unscaled, Scale: int32(len(fracPart))}, nil
+}
+
+// 转换为十进制字符串，Scale为负数时在末尾补0
+func (this Decimal) String() string {
+	unscaled := this.Unscaled
+	if unscaled == nil {
+		unscaled = new(big.Int)
+	}
+	if this.Scale <= 0 {
+		return unscaled.String() + strings.Repeat("0", int(-this.Scale))
+	}
+
+	digits := new(big.Int).Abs(unscaled).String()
+	if len(digits) <= int(this.Scale) {
+		digits = strings.Repeat("0", int(this.Scale)-len(digits)+1) + digits
+	}
+	point := len(digits) - int(this.Scale)
+
+	sign := ""
+	if unscaled.Sign() < 0 {
+		sign = "-"
+	}
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// 转换为精确的有理数
+func (this Decimal) Rat() *big.Rat {
+	ret := new(big.Rat)
+	if this.Unscaled != nil {
+		ret.SetInt(this.Unscaled)
+	}
+
+	scale := int64(this.Scale)
+	if scale < 0 {
+		scale = -scale
+	}
+	power := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(scale), nil))
+	if this.Scale >= 0 {
+		return ret.Quo(ret, power)
+	}
+	return ret.Mul(ret, power)
+}
+
+// 实现TLVMarshaler
+func (this *Decimal) MarshalTLV(tlvObject *TLVObject) error {
+	unscaled := this.Unscaled
+	if unscaled == nil {
+		unscaled = new(big.Int)
+	}
+	if err := tlvObject.PutBigInt(decimalUnscaledTag, unscaled); err != nil {
+		return err
+	}
+	return tlvObject.PutInt32(decimalScaleTag, this.Scale)
+}
+
+// 实现TLVUnmarshaler
+func (this *Decimal) UnmarshalTLV(tlvObject *TLVObject) error {
+	unscaled, err := tlvObject.GetBigIntE(decimalUnscaledTag)
+	if err != nil {
+		return err
+	}
+	scale, err := tlvObject.GetInt32E(decimalScaleTag)
+	if err != nil {
+		return err
+	}
+
+	this.Unscaled = unscaled
+	this.Scale = scale
+	return nil
+}
+
+// 写入十进制小数，编码为TLV嵌套结构
+func (this *TLVObject) PutDecimal(key int, value Decimal) error {
+	return this.PutMarshaler(key, &value)
+}
+
+func (this *TLVObject) GetDecimal(key int) (ret Decimal, ok bool) {
+	ret, err := this.GetDecimalE(key)
+	return ret, err == nil
+}
+
+func (this *TLVObject) GetDecimalE(key int) (ret Decimal, err error) {
+	err = this.GetUnmarshaler(key, &ret)
+	return ret, err
+}
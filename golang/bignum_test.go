@@ -0,0 +1,132 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golang
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestBigInt(t *testing.T) {
+	huge, _ := new(big.Int).SetString("-123456789012345678901234567890", 10)
+	cases := []struct {
+		value int64
+		want  []byte
+	}{
+		{0, []byte{0x00}},
+		{127, []byte{0x7f}},
+		{128, []byte{0x00, 0x80}},
+		{256, []byte{0x01, 0x00}},
+		{-1, []byte{0xff}},
+		{-128, []byte{0x80}},
+		{-129, []byte{0xff, 0x7f}},
+		{-256, []byte{0xff, 0x00}},
+	}
+
+	tlvBuilder := TLVObject{}
+	for i, c := range cases {
+		tlvBuilder.PutBigInt(i, big.NewInt(c.value))
+	}
+	tlvBuilder.PutBigInt(100, huge)
+
+	tlvParser := TLVObject{}
+	if err := tlvParser.FromBytes(tlvBuilder.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	for i, c := range cases {
+		if value, _ := tlvParser.GetBytes(i); bytes.Equal(value, c.want) == false {
+			t.Errorf("%d: value = %x, want = %x", c.value, value, c.want)
+		}
+		if value, ok := tlvParser.GetBigInt(i); ok == false || value.Int64() != c.value {
+			t.Errorf("value = %v, want = %d", value, c.value)
+		}
+	}
+	if value, ok := tlvParser.GetBigInt(100); ok == false || value.Cmp(huge) != 0 {
+		t.Errorf("value = %v", value)
+	}
+
+	if err := tlvBuilder.PutBigInt(1, nil); errors.Is(err, ErrInvalidParam) == false {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	for _, s := range []string{"123.45", "-0.05", "1000", "0.000"} {
+		value, err := ParseDecimal(s)
+		if err != nil || value.String() != s {
+			t.Errorf("value = %v, err = %v", value, err)
+		}
+	}
+	if _, err := ParseDecimal("1.2.3"); errors.Is(err, ErrInvalidParam) == false {
+		t.Errorf("err = %v", err)
+	}
+	if value := (Decimal{Unscaled: big.NewInt(12), Scale: -2}); value.String() != "1200" || value.Rat().Cmp(big.NewRat(1200, 1)) != 0 {
+		t.Errorf("value = %v", value)
+	}
+
+	amount, _ := ParseDecimal("-98765432109876543210.123456789")
+	order := TLVObject{}
+	order.PutInt32(1, 7)
+	order.PutDecimal(2, amount)
+
+	tlvBuilder := TLVObject{}
+	tlvBuilder.Put(0, &order)
+
+	tlvParser := TLVObject{}
+	if err := tlvParser.FromBytes(tlvBuilder.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	orderParser, _ := tlvParser.Get(0)
+	value, err := orderParser.GetDecimalE(2)
+	if err != nil || value.String() != amount.String() || value.Scale != 9 {
+		t.Errorf("value = %v, err = %v", value, err)
+	}
+	if _, err = orderParser.GetDecimalE(1); errors.Is(err, ErrTypeMismatch) == false {
+		t.Errorf("err = %v", err)
+	}
+}
+
+type testBill struct {
+	Total  *big.Int `tlv:"1"`
+	Fee    big.Int  `tlv:"2"`
+	Amount Decimal  `tlv:"3"`
+}
+
+func TestMarshalBig(t *testing.T) {
+	src := testBill{Total: big.NewInt(-1 << 40), Amount: Decimal{Unscaled: big.NewInt(1999), Scale: 2}}
+	src.Fee.SetInt64(300)
+	data, err := Marshal(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tlvBuilder := TLVObject{}
+	tlvBuilder.PutBigInt(1, src.Total)
+	tlvBuilder.PutBigInt(2, &src.Fee)
+	tlvBuilder.PutDecimal(3, src.Amount)
+	if bytes.Equal(tlvBuilder.Bytes(), data) == false {
+		t.Errorf("\n%x\n%x", tlvBuilder.Bytes(), data)
+	}
+
+	var dst testBill
+	if err = Unmarshal(data, &dst); err != nil {
+		t.Fatal(err)
+	}
+	if dst.Total.Cmp(src.Total) != 0 || dst.Fee.Cmp(&src.Fee) != 0 || dst.Amount.String() != "19.99" {
+		t.Errorf("dst = %+v", dst)
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golang
+
+import (
+	"bytes"
+	"testing"
+)
+
+// 修改子节点后Bytes返回新的编码结果
+func TestBytesAfterModify(t *testing.T) {
+	tlvObject := TLVObject{}
+	child := &TLVObject{}
+	child.PutInt32(0, 1)
+	tlvObject.Put(1, child)
+	before := append([]byte{}, tlvObject.Bytes()...)
+
+	child.SetInt32(0, 2)
+	child.PutString(1, "new")
+	after := tlvObject.Bytes()
+	if bytes.Equal(before, after) {
+		t.Fatalf("Bytes没有更新: %x", after)
+	}
+
+	tlvParser := TLVObject{}
+	if err := tlvParser.FromBytes(after); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := tlvParser.GetPathInt32("1/0"); v != 2 {
+		t.Errorf("v = %v", v)
+	}
+	if s, _ := tlvParser.GetPathString("1/1"); s != "new" {
+		t.Errorf("s = %q", s)
+	}
+
+	//TLVPkg的字段修改后重新生成字节数据
+	pkg := TLVPkg{TagValue: 1, Value: []byte{0x01}}
+	pkg.Bytes()
+	pkg.Value = []byte{0x02, 0x03}
+	if data := pkg.Bytes(); bytes.Equal(data, []byte{0x01, 0x02, 0x02, 0x03}) == false {
+		t.Errorf("data = %x", data)
+	}
+	pkg.Build()
+	if data := pkg.Bytes(); len(data) != pkg.Size() {
+		t.Errorf("data = %x", data)
+	}
+}
+
+// 只重新编码修改过的子树，未修改的子树使用解码时的原始字节
+func TestIncrementalEncode(t *testing.T) {
+	tlvBuilder := TLVObject{}
+	for i := 1; i <= 3; i++ {
+		child := &TLVObject{}
+		child.PutInt32(0, int32(i))
+		child.PutString(1, "body")
+		tlvBuilder.Put(i, child)
+	}
+	data := append([]byte{}, tlvBuilder.Bytes()...)
+
+	tlvParser := TLVObject{}
+	if err := tlvParser.FromBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	untouched, _ := tlvParser.Get(3)
+	cached := untouched.cache
+
+	header, _ := tlvParser.Get(1)
+	header.SetInt32(0, 100)
+	tlvBuilder.ChildAt(0).SetInt32(0, 100)
+
+	expect := tlvBuilder.Bytes()
+	if encoded := tlvParser.Bytes(); bytes.Equal(encoded, expect) == false {
+		t.Fatalf("encoded = %x, expect = %x", encoded, expect)
+	}
+	if &untouched.cache[0] != &cached[0] {
+		t.Errorf("未修改的子树被重新编码")
+	}
+
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(&tlvParser); err != nil || bytes.Equal(buf.Bytes(), expect) == false {
+		t.Errorf("buf = %x, err = %v", buf.Bytes(), err)
+	}
+
+	//按值复制后修改子节点，复制得到的对象同样不使用过期的缓存
+	copied := tlvParser
+	copied.Bytes()
+	copiedChild, _ := copied.Get(2)
+	copiedChild.SetInt32(0, 200)
+	tlvBuilder.ChildAt(1).SetInt32(0, 200)
+	if encoded := copied.Bytes(); bytes.Equal(encoded, tlvBuilder.Bytes()) == false {
+		t.Errorf("encoded = %x", encoded)
+	}
+	//原对象与复制对象共用子节点，同样得到修改后的结果
+	if encoded := tlvParser.Bytes(); bytes.Equal(encoded, tlvBuilder.Bytes()) == false {
+		t.Errorf("encoded = %x", encoded)
+	}
+
+	//复制对象编码后，通过原对象修改子节点
+	copied = tlvParser
+	copied.Bytes()
+	originChild, _ := tlvParser.Get(3)
+	originChild.SetInt32(0, 300)
+	tlvBuilder.ChildAt(2).SetInt32(0, 300)
+	if encoded := tlvParser.Bytes(); bytes.Equal(encoded, tlvBuilder.Bytes()) == false {
+		t.Errorf("encoded = %x", encoded)
+	}
+	if encoded := copied.Bytes(); bytes.Equal(encoded, tlvBuilder.Bytes()) == false {
+		t.Errorf("encoded = %x", encoded)
+	}
+}
+
+// 直接修改导出字段后通过MarkDirty重新编码
+func TestMarkDirty(t *testing.T) {
+	tlvBuilder := TLVObject{}
+	child := &TLVObject{}
+	child.PutInt32(2, 5)
+	tlvBuilder.Put(1, child)
+
+	tlvParser := TLVObject{}
+	if err := tlvParser.FromBytes(tlvBuilder.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	before := append([]byte{}, tlvParser.Bytes()...)
+
+	leaf, _ := tlvParser.GetPath("1/2")
+	leaf.Pkg.Value = []byte{0x00, 0x00, 0x00, 0x07}
+	leaf.MarkDirty()
+	after := tlvParser.Bytes()
+	if bytes.Equal(before, after) || after[len(after)-1] != 0x07 {
+		t.Errorf("before = %x, after = %x", before, after)
+	}
+}
+
+// 非规范形式的原始字节不用于规范形式的编码
+func TestParsedCacheCanonical(t *testing.T) {
+	data := []byte{0x21, 0x83, 0x00, 0x02, 0x01, 0x05}
+	tlvParser := TLVObject{}
+	if err := tlvParser.FromBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	if encoded := tlvParser.Bytes(); bytes.Equal(encoded, data) == false {
+		t.Errorf("encoded = %x", encoded)
+	}
+	encoded, err := tlvParser.BytesWith(Codec{Canonical: true})
+	if err != nil || bytes.Equal(encoded, []byte{0x21, 0x03, 0x02, 0x01, 0x05}) == false {
+		t.Errorf("encoded = %x, err = %v", encoded, err)
+	}
+}
@@ -0,0 +1,450 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+	"unicode"
+)
+
+// 生成代码所需的参数
+type genOptions struct {
+	source     string // IDL文件名，写入生成代码的头部注释
+	pkg        string // 生成代码的包名
+	importPath string // TLV库的导入路径
+}
+
+type generator struct {
+	file    *schemaFile
+	options genOptions
+	buf     bytes.Buffer
+	useFmt  bool
+	useTime bool
+}
+
+// 根据IDL定义生成Go代码，输出经过gofmt格式化
+func generate(file *schemaFile, options genOptions) ([]byte, error) {
+	g := &generator{file: file, options: options}
+
+	for _, enum := range file.enums {
+		g.genEnum(enum)
+	}
+	for _, message := range file.messages {
+		g.genMessage(message)
+	}
+	body := g.buf.Bytes()
+	g.buf = bytes.Buffer{}
+
+	g.p("// Code generated by tlvgen from %s. DO NOT EDIT.", options.source)
+	g.p("")
+	g.p("package %s", options.pkg)
+	g.p("")
+	g.p("import (")
+	if g.useFmt {
+		g.p("\"fmt\"")
+	}
+	if g.useTime {
+		g.p("\"time\"")
+	}
+	if g.useFmt || g.useTime {
+		g.p("")
+	}
+	g.p("tlv %q", options.importPath)
+	g.p(")")
+	g.p("")
+	g.buf.Write(body)
+
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("格式化生成的代码失败: %v", err)
+	}
+	return src, nil
+}
+
+func (this *generator) p(format string, args ...interface{}) {
+	fmt.Fprintf(&this.buf, format, args...)
+	this.buf.WriteByte('\n')
+}
+
+func (this *generator) genEnum(enum *enumDef) {
+	this.p("type %s int32", enum.name)
+	this.p("")
+	if len(enum.values) == 0 {
+		return
+	}
+	this.p("const (")
+	for _, v := range enum.values {
+		this.p("%s%s %s = %d", enum.name, goName(v.name), enum.name, v.value)
+	}
+	this.p(")")
+	this.p("")
+}
+
+func (this *generator) genMessage(message *messageDef) {
+	this.p("type %s struct {", message.name)
+	for _, field := range message.fields {
+		if field.typeName == "time" || field.typeName == "duration" {
+			this.useTime = true
+		}
+		this.p("%s %s `tlv:\"%s\"`", goName(field.name), goType(field), structTag(field))
+	}
+	this.p("")
+	this.p("// 未定义的字段，重新编码时原样输出")
+	this.p("UnknownFields tlv.UnknownFields")
+	this.p("}")
+	this.p("")
+
+	this.genMarshal(message)
+	this.genUnmarshal(message)
+
+	this.p("// 按legacy编码规则编码为TLV数据")
+	this.p("func (this *%s) Encode() ([]byte, error) {", message.name)
+	this.p("return this.EncodeWith(tlv.Codec{})")
+	this.p("}")
+	this.p("")
+
+	this.p("// 按指定的编码规则编码为TLV数据")
+	this.p("func (this *%s) EncodeWith(codec tlv.Codec) ([]byte, error) {", message.name)
+	this.p("tlvObject := &tlv.TLVObject{}")
+	this.p("if err := this.MarshalTLV(tlvObject); err != nil {")
+	this.p("return nil, err")
+	this.p("}")
+	this.p("return tlvObject.BytesWith(codec)")
+	this.p("}")
+	this.p("")
+
+	this.p("// 按legacy编码规则从TLV数据中解码")
+	this.p("func (this *%s) Decode(data []byte) error {", message.name)
+	this.p("return this.DecodeWith(data, tlv.Codec{})")
+	this.p("}")
+	this.p("")
+
+	this.p("// 按指定的编码规则从TLV数据中解码")
+	this.p("func (this *%s) DecodeWith(data []byte, codec tlv.Codec) error {", message.name)
+	this.p("tlvObject := &tlv.TLVObject{}")
+	this.p("if err := tlvObject.FromBytesWith(data, codec); err != nil {")
+	this.p("return err")
+	this.p("}")
+	this.p("return this.UnmarshalTLV(tlvObject)")
+	this.p("}")
+	this.p("")
+}
+
+func (this *generator) genMarshal(message *messageDef) {
+	this.p("// 实现tlv.TLVMarshaler")
+	this.p("func (this *%s) MarshalTLV(tlvObject *tlv.TLVObject) error {", message.name)
+	for _, field := range message.fields {
+		value := "this." + goName(field.name)
+		omitEmpty := field.omitEmpty && field.message == nil
+		if field.repeated && field.packed == false {
+			this.p("for _, v := range %s {", value)
+			value = "v"
+		} else if omitEmpty {
+			this.p("if %s {", nonZero(field, value))
+		}
+
+		if field.message != nil {
+			this.p("if %s != nil {", value)
+		}
+		this.p("if err := %s; err != nil {", putExpr(field, value))
+		this.p("return err")
+		this.p("}")
+		if field.message != nil {
+			this.p("}")
+		}
+
+		if (field.repeated && field.packed == false) || omitEmpty {
+			this.p("}")
+		}
+	}
+	this.p("this.UnknownFields.PutTo(tlvObject)")
+	this.p("return nil")
+	this.p("}")
+	this.p("")
+}
+
+func (this *generator) genUnmarshal(message *messageDef) {
+	this.p("// 实现tlv.TLVUnmarshaler，未定义的tag保存在UnknownFields中")
+	this.p("func (this *%s) UnmarshalTLV(tlvObject *tlv.TLVObject) error {", message.name)
+	this.p("*this = %s{}", message.name)
+	if hasSingleField(message) {
+		this.p("var seen [%d]bool", len(message.fields))
+	}
+	this.p("for _, child := range tlvObject.Children() {")
+	if len(message.fields) > 0 {
+		this.useFmt = true
+		this.p("switch child.Pkg.TagValue {")
+		for i, field := range message.fields {
+			this.genDecodeField(message, field, i)
+		}
+		this.p("default:")
+		this.p("this.UnknownFields.Add(child)")
+		this.p("}")
+	} else {
+		this.p("this.UnknownFields.Add(child)")
+	}
+	this.p("}")
+	this.p("return nil")
+	this.p("}")
+	this.p("")
+}
+
+// 与反射解码一致：帧类型不同的节点保存在UnknownFields中，非重复字段使用第一个匹配的节点
+func (this *generator) genDecodeField(message *messageDef, field *fieldDef, index int) {
+	name := "this." + goName(field.name)
+	errorf := fmt.Sprintf("fmt.Errorf(\"%s.%s: %%w\", err)", message.name, field.name)
+
+	this.p("case %d:", field.tag)
+	class := field.class
+	if class == "" {
+		class = "universal"
+	}
+	this.p("if child.Pkg.FrameType != %s {", classConst(class))
+	this.p("this.UnknownFields.Add(child)")
+	this.p("continue")
+	this.p("}")
+	if isSingleField(field) {
+		this.p("if seen[%d] {", index)
+		this.p("continue")
+		this.p("}")
+		this.p("seen[%d] = true", index)
+	}
+	//与反射解码一致，null解码为零值，重复字段追加一个零值元素
+	this.p("if child.Pkg.IsNull() {")
+	if field.repeated && field.packed == false {
+		this.p("var v %s", elemType(field))
+		this.p("%s = append(%s, v)", name, name)
+	}
+	this.p("continue")
+	this.p("}")
+
+	var value string
+	if field.message != nil {
+		this.p("if child.Pkg.DataType != tlv.DataTypeStruct {")
+		this.p("return fmt.Errorf(\"%s.%s: %%w\", tlv.ErrTypeMismatch)", message.name, field.name)
+		this.p("}")
+		this.p("v := &%s{}", field.message.name)
+		this.p("if err := v.UnmarshalTLV(child); err != nil {")
+		this.p("return %s", errorf)
+		this.p("}")
+		value = "v"
+	} else if field.typeName == "bytes" {
+		value = "append([]byte{}, child.Pkg.Value...)"
+	} else {
+		accessor, conversion := asExpr(field)
+		this.p("v, err := child.Pkg.%s()", accessor)
+		this.p("if err != nil {")
+		this.p("return %s", errorf)
+		this.p("}")
+		if rangeType := checkedType(field); rangeType != "" {
+			wideType := "int64"
+			if strings.HasSuffix(accessor, "Uint") {
+				wideType = "uint64"
+			}
+			this.p("if %s(%s(v)) != v {", wideType, rangeType)
+			this.p("return fmt.Errorf(\"%s.%s: %%w: 超出%s的范围\", tlv.ErrTypeMismatch)", message.name, field.name, rangeType)
+			this.p("}")
+		}
+		value = "v"
+		if conversion != "" {
+			value = conversion + "(v)"
+		}
+	}
+
+	if field.repeated && field.packed == false {
+		this.p("%s = append(%s, %s)", name, name, value)
+	} else {
+		this.p("%s = %s", name, value)
+	}
+}
+
+// 是否为只使用第一个匹配节点的字段，即非重复字段及packed字段
+func isSingleField(field *fieldDef) bool {
+	return field.repeated == false || field.packed
+}
+
+func hasSingleField(message *messageDef) bool {
+	for _, field := range message.fields {
+		if isSingleField(field) {
+			return true
+		}
+	}
+	return false
+}
+
+// varint及compact字段读取为64位整数，返回转换前需要检查范围的类型，不需要检查时返回空字符串
+func checkedType(field *fieldDef) string {
+	if field.packed || (field.varint == false && field.compact == false) {
+		return ""
+	}
+	if field.enum != nil {
+		return "int32"
+	}
+	if field.typeName == "int64" || field.typeName == "uint64" {
+		return ""
+	}
+	return field.typeName
+}
+
+// 字段的Go类型名
+func goType(field *fieldDef) string {
+	if field.repeated {
+		return "[]" + elemType(field)
+	}
+	return elemType(field)
+}
+
+// 字段的Go类型名，重复字段返回元素的类型名
+func elemType(field *fieldDef) string {
+	switch {
+	case field.message != nil:
+		return "*" + field.message.name
+	case field.enum != nil:
+		return field.enum.name
+	case field.typeName == "bytes":
+		return "[]byte"
+	case field.typeName == "time":
+		return "time.Time"
+	case field.typeName == "duration":
+		return "time.Duration"
+	default:
+		return field.typeName
+	}
+}
+
+// 与反射编码一致的结构体标签，使生成的类型也可以使用tlv.Marshal
+func structTag(field *fieldDef) string {
+	tag := fmt.Sprint(field.tag)
+	if field.omitEmpty {
+		tag += ",omitempty"
+	}
+	if field.varint {
+		tag += ",varint"
+	}
+	if field.compact {
+		tag += ",compact"
+	}
+	if field.packed {
+		tag += ",packed"
+	}
+	if field.class != "" {
+		tag += "," + field.class
+	}
+	return tag
+}
+
+func classConst(class string) string {
+	return "tlv.Class" + goName(class)
+}
+
+// 写入字段时使用的key
+func keyExpr(field *fieldDef) string {
+	if field.class != "" {
+		return fmt.Sprintf("tlv.ClassTag(%s, %d)", classConst(field.class), field.tag)
+	}
+	return fmt.Sprint(field.tag)
+}
+
+// 判断字段不为零值的表达式
+func nonZero(field *fieldDef, value string) string {
+	if field.packed {
+		return "len(" + value + ") > 0"
+	}
+	switch field.typeName {
+	case "bool":
+		return value
+	case "string":
+		return value + ` != ""`
+	case "bytes":
+		return "len(" + value + ") > 0"
+	case "time":
+		return value + ".IsZero() == false"
+	}
+	return value + " != 0"
+}
+
+// 写入一个值的表达式
+func putExpr(field *fieldDef, value string) string {
+	key := keyExpr(field)
+	switch {
+	case field.message != nil:
+		return fmt.Sprintf("tlvObject.PutMarshaler(%s, %s)", key, value)
+	case field.packed:
+		return fmt.Sprintf("tlvObject.PutPacked%ss(%s, %s)", goName(field.typeName), key, value)
+	case field.compact && strings.HasPrefix(field.typeName, "uint"):
+		return fmt.Sprintf("tlvObject.PutCompactUint(%s, uint64(%s))", key, value)
+	case field.compact:
+		return fmt.Sprintf("tlvObject.PutCompactInt(%s, int64(%s))", key, value)
+	case field.varint && strings.HasPrefix(field.typeName, "uint"):
+		return fmt.Sprintf("tlvObject.PutVarUint(%s, uint64(%s))", key, value)
+	case field.varint:
+		return fmt.Sprintf("tlvObject.PutVarInt(%s, int64(%s))", key, value)
+	case field.enum != nil:
+		return fmt.Sprintf("tlvObject.PutInt32(%s, int32(%s))", key, value)
+	case field.typeName == "string":
+		return fmt.Sprintf("tlvObject.PutBytes(%s, []byte(%s))", key, value)
+	case field.typeName == "bytes":
+		return fmt.Sprintf("tlvObject.PutBytes(%s, %s)", key, value)
+	}
+	return fmt.Sprintf("tlvObject.Put%s(%s, %s)", goName(field.typeName), key, value)
+}
+
+// 读取基本数据时使用的TLVPkg方法，以及需要的类型转换
+func asExpr(field *fieldDef) (accessor string, conversion string) {
+	switch {
+	case field.packed:
+		return "AsPacked" + goName(field.typeName) + "s", ""
+	case field.compact && strings.HasPrefix(field.typeName, "uint"):
+		accessor = "AsCompactUint"
+	case field.compact:
+		accessor = "AsCompactInt"
+	case field.varint && strings.HasPrefix(field.typeName, "uint"):
+		accessor = "AsVarUint"
+	case field.varint:
+		accessor = "AsVarInt"
+	case field.enum != nil:
+		accessor = "AsInt32"
+	default:
+		return "As" + goName(field.typeName), ""
+	}
+
+	if field.enum != nil {
+		return accessor, field.enum.name
+	}
+	if field.typeName != "int64" && field.typeName != "uint64" {
+		return accessor, field.typeName
+	}
+	return accessor, ""
+}
+
+// 将IDL中的名称转换为导出的Go名称，如user_id转换为UserId，ACTIVE转换为Active
+func goName(name string) string {
+	var ret strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		if part == "" {
+			continue
+		}
+		runes := []rune(part)
+		if strings.ToUpper(part) == part {
+			runes = []rune(strings.ToLower(part))
+		}
+		runes[0] = unicode.ToUpper(runes[0])
+		ret.WriteString(string(runes))
+	}
+	return ret.String()
+}
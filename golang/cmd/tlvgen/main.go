@@ -0,0 +1,110 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// tlvgen根据IDL文件生成不依赖反射的TLV编解码代码
+//
+// 用法:
+//
+//	tlvgen [-package name] [-import path] [-o output.go] file.tlv
+//
+// IDL文件由可选的package声明、enum及message定义组成，支持//注释:
+//
+//	package demo;
+//
+//	enum Status {
+//		UNKNOWN = 0;
+//		ACTIVE = 1;
+//	}
+//
+//	message User {
+//		uint64 id = 1 [varint];
+//		string name = 2;
+//		Status status = 3;
+//		repeated string tags = 4 [omitempty];
+//		Address address = 5 [context];
+//	}
+//
+// 字段类型为bool、int8至uint64、float32、float64、string、bytes、time、duration，或文件中定义的enum及message，
+// time及duration对应time.Time及time.Duration，按PutTime及PutDuration的方式编码，
+// repeated字段编码为多个tag相同的节点，message字段编码为TLV嵌套结构。
+// 字段选项与tlv.Marshal的结构体标签一致: omitempty、varint、compact、packed以及帧类型application、context、private。
+//
+// 每个message生成一个结构体，以及MarshalTLV/UnmarshalTLV和Encode/EncodeWith/Decode/DecodeWith方法，
+// 编码结果与使用Put系列函数手动构建的TLVObject相同。
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const defaultImportPath = "github.com/charliexp/TLV-1/golang"
+
+func main() {
+	pkg := flag.String("package", "", "生成代码的包名，默认使用IDL文件中的package声明")
+	importPath := flag.String("import", defaultImportPath, "TLV库的导入路径")
+	output := flag.String("o", "", "输出文件，默认为IDL文件名加.go后缀")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "用法: tlvgen [flags] file.tlv\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(flag.Arg(0), *output, *pkg, *importPath); err != nil {
+		fmt.Fprintln(os.Stderr, "tlvgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(input string, output string, pkg string, importPath string) error {
+	src, err := ioutil.ReadFile(input)
+	if err != nil {
+		return err
+	}
+
+	file, err := parseSchema(filepath.Base(input), string(src))
+	if err != nil {
+		return err
+	}
+
+	if pkg == "" {
+		pkg = file.pkg
+	}
+	if pkg == "" {
+		return fmt.Errorf("%s: 没有package声明，需要通过-package指定包名", input)
+	}
+
+	code, err := generate(file, genOptions{
+		source:     filepath.Base(input),
+		pkg:        pkg,
+		importPath: importPath,
+	})
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		output = strings.TrimSuffix(input, filepath.Ext(input)) + ".go"
+	}
+	return ioutil.WriteFile(output, code, 0644)
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "更新testdata中的golden文件")
+
+func generateExample(t *testing.T) []byte {
+	src, err := ioutil.ReadFile("testdata/example.tlv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := parseSchema("example.tlv", string(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := generate(file, genOptions{source: "example.tlv", pkg: file.pkg, importPath: defaultImportPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func TestGolden(t *testing.T) {
+	code := generateExample(t)
+	if *update {
+		if err := ioutil.WriteFile("testdata/example.golden", code, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := ioutil.ReadFile("testdata/example.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(code, want) == false {
+		t.Errorf("生成的代码与testdata/example.golden不一致，可以使用-update更新")
+	}
+}
+
+func TestParseError(t *testing.T) {
+	cases := []struct {
+		src string
+		msg string
+	}{
+		{"message A { int32 a = 1; int32 b = 1; }", "tag 1重复使用"},
+		{"message A { int32 a = 1; string a = 2; }", "字段a重复定义"},
+		{"message A { float a = 1; }", "未定义的类型float"},
+		{"message A { string a = 1 [varint]; }", "不能使用varint及compact选项"},
+		{"message A { int32 a = 1 [varint, compact]; }", "不能同时使用"},
+		{"message A { int32 a = 1 [packed]; }", "packed选项只能用于"},
+		{"message A { repeated string a = 1 [packed]; }", "packed选项只能用于"},
+		{"message A { int32 a = 1 [fixed]; }", "未知的字段选项"},
+		{"message A { int32 a = -1; }", "超出范围"},
+		{"message A {\n int32 a = 1\n}", "example.tlv:3: 需要\";\""},
+		{"enum A { X = 0; }\nmessage A {}", "类型A重复定义"},
+		{"message A { bytes unknown_fields = 1; }", "与生成的代码冲突"},
+	}
+
+	for _, c := range cases {
+		_, err := parseSchema("example.tlv", c.src)
+		if err == nil || strings.Contains(err.Error(), c.msg) == false {
+			t.Errorf("%q: 错误应包含%q, 实际为%v", c.src, c.msg, err)
+		}
+	}
+}
+
+// 在临时GOPATH中编译生成的代码，与手动构建的TLVObject互相转换
+func TestGeneratedCode(t *testing.T) {
+	if testing.Short() {
+		t.Skip("需要调用go命令编译生成的代码")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("找不到go命令")
+	}
+
+	repoRoot, err := filepath.Abs("../../..")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gopath, err := ioutil.TempDir("", "tlvgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(gopath)
+
+	libDir := filepath.Join(gopath, "src", filepath.FromSlash(defaultImportPath))
+	if err = os.MkdirAll(filepath.Dir(filepath.Dir(libDir)), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Symlink(repoRoot, filepath.Dir(libDir)); err != nil {
+		t.Skip("无法创建符号链接:", err)
+	}
+
+	exampleDir := filepath.Join(gopath, "src", "example")
+	if err = os.MkdirAll(exampleDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(exampleDir, "example.go"), generateExample(t), 0644); err != nil {
+		t.Fatal(err)
+	}
+	roundTrip, err := ioutil.ReadFile("testdata/roundtrip_test.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(exampleDir, "roundtrip_test.go"), roundTrip, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(goTool, "test", "example")
+	cmd.Dir = exampleDir
+	cmd.Env = append(os.Environ(), "GOPATH="+gopath, "GO111MODULE=off", "GOFLAGS=")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("生成的代码测试失败: %v\n%s", err, output)
+	}
+}
@@ -0,0 +1,419 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// IDL文件描述的全部定义
+type schemaFile struct {
+	pkg      string
+	enums    []*enumDef
+	messages []*messageDef
+}
+
+type enumDef struct {
+	name   string
+	values []enumValue
+}
+
+type enumValue struct {
+	name  string
+	value int32
+}
+
+type messageDef struct {
+	name   string
+	fields []*fieldDef
+}
+
+// 消息中的一个字段，对应一个子节点
+type fieldDef struct {
+	name      string
+	typeName  string
+	tag       int
+	repeated  bool
+	omitEmpty bool
+	varint    bool
+	compact   bool
+	packed    bool
+	class     string // 帧类型，为空时为universal
+
+	enum    *enumDef    // 字段为枚举类型时有效
+	message *messageDef // 字段为消息类型时有效
+	line    int
+}
+
+// 支持的基本数据类型
+var scalarTypes = map[string]bool{
+	"bool": true, "string": true, "bytes": true,
+	"int8": true, "uint8": true, "int16": true, "uint16": true,
+	"int32": true, "uint32": true, "int64": true, "uint64": true,
+	"float32": true, "float64": true, "time": true, "duration": true,
+}
+
+// 可以使用varint选项的整数类型
+func isIntegerType(typeName string) bool {
+	return scalarTypes[typeName] && (strings.HasPrefix(typeName, "int") || strings.HasPrefix(typeName, "uint"))
+}
+
+// 可以使用packed选项的类型
+var packedTypes = map[string]bool{
+	"int32": true, "int64": true, "uint32": true, "uint64": true, "float32": true, "float64": true,
+}
+
+// 生成代码中已经使用的名称，字段不能使用
+var reservedNames = map[string]bool{
+	"UnknownFields": true, "MarshalTLV": true, "UnmarshalTLV": true,
+	"Encode": true, "EncodeWith": true, "Decode": true, "DecodeWith": true,
+}
+
+// tag允许的最大值，与ClassTag的限制一致
+const maxTagValue = 1<<28 - 1
+
+type token struct {
+	text string
+	line int
+}
+
+// 将IDL文本切分为标识符、数字及符号，忽略//注释
+func tokenize(src string) []token {
+	var tokens []token
+	line := 1
+	runes := []rune(src)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == '\n':
+			line++
+			i++
+		case unicode.IsSpace(r):
+			i++
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-':
+			start := i
+			for i++; i < len(runes) && (runes[i] == '_' || unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])); i++ {
+			}
+			tokens = append(tokens, token{string(runes[start:i]), line})
+		default:
+			tokens = append(tokens, token{string(r), line})
+			i++
+		}
+	}
+	return tokens
+}
+
+type parser struct {
+	filename string
+	tokens   []token
+	pos      int
+}
+
+// 解析IDL文件，并检查类型引用、tag及名称是否合法
+func parseSchema(filename string, src string) (*schemaFile, error) {
+	p := &parser{filename: filename, tokens: tokenize(src)}
+	file, err := p.parseFile()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.resolve(file); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+func (this *parser) errorf(line int, format string, args ...interface{}) error {
+	return fmt.Errorf("%s:%d: %s", this.filename, line, fmt.Sprintf(format, args...))
+}
+
+func (this *parser) peek() token {
+	if this.pos < len(this.tokens) {
+		return this.tokens[this.pos]
+	}
+	line := 1
+	if len(this.tokens) > 0 {
+		line = this.tokens[len(this.tokens)-1].line
+	}
+	return token{"", line}
+}
+
+func (this *parser) next() token {
+	tok := this.peek()
+	if this.pos < len(this.tokens) {
+		this.pos++
+	}
+	return tok
+}
+
+func (this *parser) expect(text string) error {
+	tok := this.next()
+	if tok.text != text {
+		return this.errorf(tok.line, "需要%q, 实际为%q", text, tok.text)
+	}
+	return nil
+}
+
+func (this *parser) ident() (token, error) {
+	tok := this.next()
+	if isIdent(tok.text) == false {
+		return tok, this.errorf(tok.line, "需要标识符, 实际为%q", tok.text)
+	}
+	return tok, nil
+}
+
+func isIdent(text string) bool {
+	if text == "" {
+		return false
+	}
+	for i, r := range text {
+		if r != '_' && unicode.IsLetter(r) == false && (i == 0 || unicode.IsDigit(r) == false) {
+			return false
+		}
+	}
+	return true
+}
+
+func (this *parser) number(bitSize int) (int64, token, error) {
+	tok := this.next()
+	n, err := strconv.ParseInt(tok.text, 0, bitSize)
+	if err != nil {
+		return 0, tok, this.errorf(tok.line, "无效的数字%q", tok.text)
+	}
+	return n, tok, nil
+}
+
+func (this *parser) parseFile() (*schemaFile, error) {
+	file := &schemaFile{}
+	for this.pos < len(this.tokens) {
+		tok := this.next()
+		switch tok.text {
+		case "package":
+			name, err := this.ident()
+			if err != nil {
+				return nil, err
+			}
+			if err = this.expect(";"); err != nil {
+				return nil, err
+			}
+			file.pkg = name.text
+		case "enum":
+			enum, err := this.parseEnum()
+			if err != nil {
+				return nil, err
+			}
+			file.enums = append(file.enums, enum)
+		case "message":
+			message, err := this.parseMessage()
+			if err != nil {
+				return nil, err
+			}
+			file.messages = append(file.messages, message)
+		default:
+			return nil, this.errorf(tok.line, "未知的定义%q", tok.text)
+		}
+	}
+	return file, nil
+}
+
+// enum Name { A = 0; B = 1; }
+func (this *parser) parseEnum() (*enumDef, error) {
+	name, err := this.ident()
+	if err != nil {
+		return nil, err
+	}
+	if err = this.expect("{"); err != nil {
+		return nil, err
+	}
+
+	enum := &enumDef{name: name.text}
+	for this.peek().text != "}" {
+		valueName, err := this.ident()
+		if err != nil {
+			return nil, err
+		}
+		if err = this.expect("="); err != nil {
+			return nil, err
+		}
+		value, _, err := this.number(32)
+		if err != nil {
+			return nil, err
+		}
+		if err = this.expect(";"); err != nil {
+			return nil, err
+		}
+		for _, v := range enum.values {
+			if v.name == valueName.text {
+				return nil, this.errorf(valueName.line, "枚举%s中%s重复定义", enum.name, valueName.text)
+			}
+		}
+		enum.values = append(enum.values, enumValue{valueName.text, int32(value)})
+	}
+	this.next()
+	return enum, nil
+}
+
+// message Name { [repeated] type name = tag [options]; }
+func (this *parser) parseMessage() (*messageDef, error) {
+	name, err := this.ident()
+	if err != nil {
+		return nil, err
+	}
+	if err = this.expect("{"); err != nil {
+		return nil, err
+	}
+
+	message := &messageDef{name: name.text}
+	for this.peek().text != "}" {
+		field, err := this.parseField()
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range message.fields {
+			if f.name == field.name {
+				return nil, this.errorf(field.line, "消息%s中字段%s重复定义", message.name, field.name)
+			}
+			if f.tag == field.tag {
+				return nil, this.errorf(field.line, "消息%s中tag %d重复使用", message.name, field.tag)
+			}
+		}
+		message.fields = append(message.fields, field)
+	}
+	this.next()
+	return message, nil
+}
+
+func (this *parser) parseField() (*fieldDef, error) {
+	typeName, err := this.ident()
+	if err != nil {
+		return nil, err
+	}
+
+	field := &fieldDef{line: typeName.line}
+	if typeName.text == "repeated" {
+		field.repeated = true
+		if typeName, err = this.ident(); err != nil {
+			return nil, err
+		}
+	}
+	field.typeName = typeName.text
+
+	name, err := this.ident()
+	if err != nil {
+		return nil, err
+	}
+	field.name = name.text
+	if reservedNames[goName(field.name)] {
+		return nil, this.errorf(name.line, "字段名%s与生成的代码冲突", field.name)
+	}
+
+	if err = this.expect("="); err != nil {
+		return nil, err
+	}
+	tag, tagToken, err := this.number(64)
+	if err != nil {
+		return nil, err
+	}
+	if tag < 0 || tag > maxTagValue {
+		return nil, this.errorf(tagToken.line, "tag %d超出范围", tag)
+	}
+	field.tag = int(tag)
+
+	if this.peek().text == "[" {
+		this.next()
+		for {
+			option, err := this.ident()
+			if err != nil {
+				return nil, err
+			}
+			if err = field.setOption(option.text); err != nil {
+				return nil, this.errorf(option.line, "%v", err)
+			}
+			if this.peek().text != "," {
+				break
+			}
+			this.next()
+		}
+		if err = this.expect("]"); err != nil {
+			return nil, err
+		}
+	}
+	return field, this.expect(";")
+}
+
+func (this *fieldDef) setOption(option string) error {
+	switch option {
+	case "omitempty":
+		this.omitEmpty = true
+	case "varint":
+		this.varint = true
+	case "compact":
+		this.compact = true
+	case "packed":
+		this.packed = true
+	case "application", "context", "private":
+		if this.class != "" {
+			return fmt.Errorf("字段%s重复指定帧类型", this.name)
+		}
+		this.class = option
+	default:
+		return fmt.Errorf("未知的字段选项%q", option)
+	}
+	return nil
+}
+
+// 解析字段引用的枚举及消息类型
+func (this *parser) resolve(file *schemaFile) error {
+	enums := map[string]*enumDef{}
+	messages := map[string]*messageDef{}
+	for _, enum := range file.enums {
+		if enums[enum.name] != nil || scalarTypes[enum.name] {
+			return fmt.Errorf("%s: 类型%s重复定义", this.filename, enum.name)
+		}
+		enums[enum.name] = enum
+	}
+	for _, message := range file.messages {
+		if enums[message.name] != nil || messages[message.name] != nil || scalarTypes[message.name] {
+			return fmt.Errorf("%s: 类型%s重复定义", this.filename, message.name)
+		}
+		messages[message.name] = message
+	}
+
+	for _, message := range file.messages {
+		for _, field := range message.fields {
+			field.enum = enums[field.typeName]
+			field.message = messages[field.typeName]
+			if field.enum == nil && field.message == nil && scalarTypes[field.typeName] == false {
+				return this.errorf(field.line, "未定义的类型%s", field.typeName)
+			}
+			if (field.varint || field.compact) && field.enum == nil && isIntegerType(field.typeName) == false {
+				return this.errorf(field.line, "字段%s的类型%s不能使用varint及compact选项", field.name, field.typeName)
+			}
+			if field.varint && field.compact {
+				return this.errorf(field.line, "字段%s不能同时使用varint及compact选项", field.name)
+			}
+			if field.packed && (field.repeated == false || packedTypes[field.typeName] == false || field.varint || field.compact) {
+				return this.errorf(field.line, "packed选项只能用于定长数值类型的repeated字段%s", field.name)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package example
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	tlv "github.com/charliexp/TLV-1/golang"
+)
+
+// 解码得到的时间为UTC时间
+var testCreated = time.Unix(1700000000, 0).UTC()
+
+// 使用Put系列函数手动构建与testUser相同的TLVObject
+func buildUser() *tlv.TLVObject {
+	root := &tlv.TLVObject{}
+	root.PutVarUint(1, 300)
+	root.PutString(2, "alice")
+	root.PutBool(3, true)
+	root.PutInt8(4, -2)
+	root.PutUint16(5, 8080)
+	root.PutInt32(tlv.ClassTag(tlv.ClassContext, 6), -7)
+	root.PutVarInt(7, 1<<40)
+	root.PutInt32(8, int32(StatusActive))
+
+	address := &tlv.TLVObject{}
+	address.PutString(1, "shenzhen")
+	address.PutString(2, "nanshan")
+	root.Put(10, address)
+
+	root.PutString(11, "a")
+	root.PutString(11, "b")
+
+	history := &tlv.TLVObject{}
+	history.PutString(1, "beijing")
+	root.Put(tlv.ClassTag(tlv.ClassApplication, 12), history)
+
+	root.PutInt16(13, 1)
+	root.PutInt16(13, -1)
+	root.PutFloat32(14, 0.5)
+	root.PutFloat64(15, 12.25)
+	root.PutTime(16, testCreated)
+	root.PutDuration(17, time.Second)
+	root.PutCompactInt(18, -300)
+	root.PutCompactInt(19, int64(StatusDisabled))
+	root.PutPackedFloat64s(20, []float64{0.25, -1})
+	return root
+}
+
+func testUser() *User {
+	return &User{
+		Id:       300,
+		Name:     "alice",
+		Admin:    true,
+		Level:    -2,
+		Port:     8080,
+		Score:    -7,
+		Balance:  1 << 40,
+		Status:   StatusActive,
+		Address:  &Address{City: "shenzhen", Street: "nanshan"},
+		Tags:     []string{"a", "b"},
+		History:  []*Address{{City: "beijing"}},
+		Codes:    []int16{1, -1},
+		Ratio:    0.5,
+		Amount:   12.25,
+		Created:  testCreated,
+		Ttl:      time.Second,
+		Delta:    -300,
+		Previous: StatusDisabled,
+		Samples:  []float64{0.25, -1},
+	}
+}
+
+// context帧类型需要BER编码规则
+var berCodec = tlv.Codec{Profile: tlv.ProfileBER}
+
+// Address中没有定义的字段
+var contextTag7 = tlv.ClassTag(tlv.ClassContext, 7)
+
+func TestGeneratedRoundTrip(t *testing.T) {
+	want, err := buildUser().BytesWith(berCodec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := testUser().EncodeWith(berCodec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(data, want) == false {
+		t.Fatalf("生成代码的编码结果与手动构建的不一致:\n%v\n%v", data, want)
+	}
+
+	reflectObject, err := tlv.MarshalObject(testUser())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflectData, _ := reflectObject.BytesWith(berCodec); bytes.Equal(reflectData, want) == false {
+		t.Errorf("生成代码的编码结果与tlv.Marshal不一致")
+	}
+
+	user := &User{}
+	if err = user.DecodeWith(want, berCodec); err != nil {
+		t.Fatal(err)
+	}
+	if reflect.DeepEqual(user, testUser()) == false {
+		t.Errorf("解码结果错误: %+v", user)
+	}
+}
+
+func TestGeneratedDecodeError(t *testing.T) {
+	root := &tlv.TLVObject{}
+	root.PutInt32(4, 1)
+
+	user := &User{}
+	if err := user.UnmarshalTLV(root); err == nil {
+		t.Errorf("字段长度错误时应返回错误")
+	}
+
+	root = &tlv.TLVObject{}
+	root.PutInt32(6, 1)
+	root.PutString(99, "unknown")
+	if err := user.UnmarshalTLV(root); err != nil || user.Score != 0 {
+		t.Errorf("帧类型不同及未定义的tag应被忽略: %v", err)
+	}
+}
+
+// 与User字段相同但没有生成的方法，使用反射解码
+type reflectUser User
+
+// 生成代码与反射解码的结果一致：帧类型不同的节点为未定义字段，重复的非重复字段使用第一个，超出范围时返回错误
+func TestGeneratedMatchesReflection(t *testing.T) {
+	root := &tlv.TLVObject{}
+	root.PutString(2, "first")
+	root.PutString(2, "second")
+	root.PutString(tlv.ClassTag(tlv.ClassApplication, 4), "app")
+	root.PutCompactInt(18, 5)
+	root.PutCompactInt(18, 6)
+
+	user, reflected := &User{}, &reflectUser{}
+	if err := user.UnmarshalTLV(root); err != nil {
+		t.Fatal(err)
+	}
+	if err := tlv.UnmarshalObject(root, reflected); err != nil {
+		t.Fatal(err)
+	}
+	if user.Name != "first" || user.Delta != 5 || len(user.UnknownFields) != 1 {
+		t.Errorf("user = %+v", user)
+	}
+	if reflect.DeepEqual((*reflectUser)(user), reflected) == false {
+		t.Errorf("与反射解码的结果不一致:\n%+v\n%+v", user, reflected)
+	}
+
+	//null解码为零值，重复字段中的null为一个零值元素
+	root = &tlv.TLVObject{}
+	for _, key := range []int{2, 3, 9, 10, 16, 20} {
+		root.PutNull(key)
+	}
+	root.PutString(11, "a")
+	root.PutNull(11)
+	root.PutNull(tlv.ClassTag(tlv.ClassApplication, 12))
+	user, reflected = &User{Name: "old"}, &reflectUser{Name: "old"}
+	if err := user.UnmarshalTLV(root); err != nil {
+		t.Fatal(err)
+	}
+	if err := tlv.UnmarshalObject(root, reflected); err != nil {
+		t.Fatal(err)
+	}
+	if user.Name != "" || user.Address != nil || reflect.DeepEqual(user.Tags, []string{"a", ""}) == false || len(user.History) != 1 || user.History[0] != nil {
+		t.Errorf("user = %+v", user)
+	}
+	if reflect.DeepEqual((*reflectUser)(user), reflected) == false {
+		t.Errorf("与反射解码的结果不一致:\n%+v\n%+v", user, reflected)
+	}
+
+	root = &tlv.TLVObject{}
+	root.PutCompactInt(18, 1<<40)
+	if err := user.UnmarshalTLV(root); errors.Is(err, tlv.ErrTypeMismatch) == false {
+		t.Errorf("err = %v", err)
+	}
+	if err := tlv.UnmarshalObject(root, reflected); errors.Is(err, tlv.ErrTypeMismatch) == false {
+		t.Errorf("err = %v", err)
+	}
+}
+
+// 未定义的字段在重新编码后保持不变
+func TestGeneratedUnknownFields(t *testing.T) {
+	root := buildUser()
+	root.PutString(99, "from newer version")
+	address, _ := root.Get(10)
+	address.PutVarInt(contextTag7, -1)
+	data, err := root.BytesWith(berCodec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := &User{}
+	if err = user.DecodeWith(data, berCodec); err != nil {
+		t.Fatal(err)
+	}
+	if len(user.UnknownFields) != 1 || len(user.Address.UnknownFields) != 1 {
+		t.Fatalf("未定义的字段没有保存: %v, %v", user.UnknownFields, user.Address.UnknownFields)
+	}
+
+	user.Name = "bob"
+	encoded, err := user.EncodeWith(berCodec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reparsed := &tlv.TLVObject{}
+	if err = reparsed.FromBytesWith(encoded, berCodec); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := reparsed.GetString(99); value != "from newer version" {
+		t.Errorf("value = %q", value)
+	}
+	reparsedAddress, _ := reparsed.Get(10)
+	if value, ok := reparsedAddress.GetVarInt(contextTag7); ok == false || value != -1 {
+		t.Errorf("value = %d, ok = %v", value, ok)
+	}
+	if name, _ := reparsed.GetString(2); name != "bob" {
+		t.Errorf("name = %q", name)
+	}
+}
@@ -0,0 +1,465 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// 实现TLV编码规则的选择
+package golang
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// 编码规则
+type Profile int
+
+const (
+	ProfileLegacy Profile = iota // 现有规则：长度及tag扩展字节为小端varint，tag扩展首字节为0x80
+	ProfileBER                   // 文档规则：长形式长度为0x8N加N字节大端长度，tag扩展时首字节0~4位全部置1
+)
+
+// ProfileBER与X.690的差异：null(见PutNull)编码为不定长方式的基本数据，X.690只允许嵌套结构使用不定长方式，
+// 严格按X.690实现的解码器会拒绝这样的数据，与这类系统交互时不应使用null
+
+// TLV编解码配置，零值使用ProfileLegacy
+type Codec struct {
+	Profile Profile // 编码规则
+
+	// 编码时使用规范形式：最短的tag及长度编码，子节点按类型及tag值稳定排序，不使用不定长方式
+	// 同一个TLVObject总是得到相同的字节数据，可用于计算摘要及签名
+	Canonical bool
+
+	// 解码时拒绝非规范形式的数据
+	Strict bool
+}
+
+var (
+	ErrNonCanonical = errors.New("非规范编码")
+	ErrInvalidClass = errors.New("当前编码规则不支持该帧类型")
+)
+
+/**
+生成tag字节数据
+*/
+func (this Codec) buildTag(frameType Class, dataType byte, tagValue int) ([]byte, error) {
+	if this.Profile == ProfileBER {
+		return buildBERTag(byte(frameType), dataType, tagValue), nil
+	}
+	if frameType&0x80 != 0 {
+		return nil, ErrInvalidClass
+	}
+	return buildTag(byte(frameType), dataType, tagValue), nil
+}
+
+/**
+生成长度字节数据
+*/
+func (this Codec) buildLength(length int) []byte {
+	if this.Profile == ProfileBER {
+		return buildBERLength(length)
+	}
+	return buildLength(length)
+}
+
+/**
+不定长方式的长度字节数据
+*/
+func (this Codec) indefiniteLength() []byte {
+	if this.Profile == ProfileBER {
+		return berIndefiniteLength
+	}
+	return indefiniteLength
+}
+
+/**
+查找tag部分占多少字节，数据不完整时返回0
+*/
+func (this Codec) tagByteCount(tlvBytes []byte) int {
+	if this.Profile == ProfileBER {
+		return berTagByteCount(tlvBytes)
+	}
+	return findTagByteCount(tlvBytes)
+}
+
+/**
+查找length部分占多少字节，数据不完整时返回0
+*/
+func (this Codec) lenByteCount(lenBytes []byte) int {
+	if this.Profile == ProfileBER {
+		return berLenByteCount(lenBytes)
+	}
+	return findLenByteCount(lenBytes, 0)
+}
+
+/**
+解析数据类型，tag值最多28位
+*/
+func (this Codec) parseTag(tagBytes []byte) (frameType Class, dataType byte, tagValue int, err error) {
+	if len(tagBytes) > 5 {
+		return 0, 0, 0, ErrTagOverflow
+	}
+
+	var rawFrameType byte
+	if this.Profile == ProfileBER {
+		rawFrameType, dataType, tagValue = parseBERTag(tagBytes)
+	} else {
+		rawFrameType, dataType, tagValue = parseTag(tagBytes)
+	}
+
+	if tagValue > maxClassTagValue {
+		return 0, 0, 0, ErrTagOverflow
+	}
+	return Class(rawFrameType), dataType, tagValue, nil
+}
+
+/**
+解析数据长度，indefinite表示不定长方式
+*/
+func (this Codec) parseLength(lenBytes []byte) (length int, indefinite bool, err error) {
+	if this.Profile == ProfileBER {
+		return parseBERLength(lenBytes)
+	}
+	if isIndefiniteLength(lenBytes) {
+		return 0, true, nil
+	}
+
+	if len(lenBytes) > 5 {
+		return 0, false, ErrLengthOverflow
+	}
+	length = parseLength(lenBytes)
+	if length < 0 || length > math.MaxInt32 {
+		return 0, false, ErrLengthOverflow
+	}
+	return length, false, nil
+}
+
+/**
+解析TLV包的tag及length字段，结果写入pkg
+不定长方式的嵌套结构不查找结束标记，pkg.Value为nil，数据段由调用方在解析子包的同时确定
+基本数据使用不定长方式时只能为null，数据段必须为空
+数据不完整时ok为false
+*/
+func (this Codec) parseTagLength(tlvBytes []byte, pkg *TLVPkg) (ok bool, err error) {
+	tagByteCount := this.tagByteCount(tlvBytes)
+	if tagByteCount == 0 {
+		return false, nil
+	}
+
+	lenByteCount := this.lenByteCount(tlvBytes[tagByteCount:])
+	if lenByteCount == 0 {
+		return false, nil
+	}
+
+	headLen := tagByteCount + lenByteCount
+	length, indefinite, err := this.parseLength(tlvBytes[tagByteCount:headLen])
+	if err != nil {
+		return false, err
+	}
+
+	if this.Strict {
+		err = this.checkCanonical(tlvBytes[:tagByteCount], tlvBytes[tagByteCount:headLen], length, indefinite)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	pkg.FrameType, pkg.DataType, pkg.TagValue, err = this.parseTag(tlvBytes[:tagByteCount])
+	if err != nil {
+		return false, err
+	}
+
+	dataByteCount := length
+	if indefinite {
+		if pkg.DataType == DataTypeStruct {
+			pkg.Value = nil
+			pkg.Indefinite = true
+			pkg.tagByteCount = tagByteCount
+			pkg.lenByteCount = lenByteCount
+			pkg.dataByteCount = 0
+			return true, nil
+		}
+		if len(tlvBytes)-headLen < len(endOfContents) {
+			return false, nil
+		}
+		if startsWithEndOfContents(tlvBytes[headLen:]) == false {
+			return false, fmt.Errorf("%w: null的数据段不为空", ErrTypeMismatch)
+		}
+		dataByteCount = len(endOfContents)
+	} else if length > len(tlvBytes)-headLen {
+		return false, nil
+	}
+
+	pkg.Value = tlvBytes[headLen : headLen+length]
+	pkg.Indefinite = indefinite
+	pkg.tagByteCount = tagByteCount
+	pkg.lenByteCount = lenByteCount
+	pkg.dataByteCount = dataByteCount
+
+	return true, nil
+}
+
+/**
+解析TLV包的tag及length字段，并确定数据段的位置，结果写入pkg
+不定长方式的嵌套结构需要扫描整个数据段，逐个解析子包时应使用parseTagLength，避免重复扫描
+数据不完整时ok为false
+*/
+func (this Codec) parseHead(tlvBytes []byte, pkg *TLVPkg) (ok bool, err error) {
+	ok, err = this.parseTagLength(tlvBytes, pkg)
+	if ok == false || err != nil || pkg.Indefinite == false || pkg.DataType != DataTypeStruct {
+		return ok, err
+	}
+
+	headLen := pkg.tagByteCount + pkg.lenByteCount
+	length, ok, err := this.findIndefiniteValueLen(tlvBytes[headLen:])
+	if ok == false || err != nil {
+		return false, err
+	}
+	pkg.Value = tlvBytes[headLen : headLen+length]
+	pkg.dataByteCount = length + len(endOfContents)
+
+	return true, nil
+}
+
+/**
+查找不定长方式的数据段长度（不含结束标记），嵌套的不定长数据会递归处理
+数据不完整时ok为false
+*/
+func (this Codec) findIndefiniteValueLen(valueBytes []byte) (valueLen int, ok bool, err error) {
+	for {
+		if valueLen+len(endOfContents) > len(valueBytes) {
+			return 0, false, nil
+		}
+
+		if startsWithEndOfContents(valueBytes[valueLen:]) {
+			return valueLen, true, nil
+		}
+
+		pkg := TLVPkg{}
+		ok, err = this.parseHead(valueBytes[valueLen:], &pkg)
+		if ok == false || err != nil {
+			return 0, false, err
+		}
+		valueLen += pkg.Size()
+	}
+}
+
+/**
+数据是否以不定长方式的结束标记开始
+*/
+func startsWithEndOfContents(tlvBytes []byte) bool {
+	return len(tlvBytes) >= len(endOfContents) && tlvBytes[0] == endOfContents[0] && tlvBytes[1] == endOfContents[1]
+}
+
+/**
+检查tag及长度字段是否为规范形式
+*/
+func (this Codec) checkCanonical(tagBytes []byte, lenBytes []byte, length int, indefinite bool) error {
+	_, dataType, tagValue, err := this.parseTag(tagBytes)
+	if err != nil {
+		return err
+	}
+
+	//null固定使用不定长方式
+	if indefinite && dataType == DataTypeStruct {
+		return fmt.Errorf("%w: 使用了不定长方式", ErrNonCanonical)
+	}
+	lastByte := len(lenBytes) - 1
+
+	if this.Profile == ProfileBER {
+		if len(tagBytes) > 1 && (tagValue < 0x1f || tagBytes[1] == 0x80) {
+			return fmt.Errorf("%w: tag %d 不是最短编码 %x", ErrNonCanonical, tagValue, tagBytes)
+		}
+		if lastByte > 0 && (length < 0x80 || lenBytes[1] == 0) {
+			return fmt.Errorf("%w: 长度 %d 不是最短编码 %x", ErrNonCanonical, length, lenBytes)
+		}
+		return nil
+	}
+
+	tagLastByte := len(tagBytes) - 1
+	if tagLastByte > 0 && (tagValue <= 0x1f || tagBytes[0]&0x1f != 0 || (tagLastByte > 1 && tagBytes[tagLastByte] == 0)) {
+		return fmt.Errorf("%w: tag %d 不是最短编码 %x", ErrNonCanonical, tagValue, tagBytes)
+	}
+	if indefinite == false && lastByte > 0 && lenBytes[lastByte] == 0 {
+		return fmt.Errorf("%w: 长度 %d 不是最短编码 %x", ErrNonCanonical, length, lenBytes)
+	}
+	return nil
+}
+
+/**
+比较两个节点的规范顺序，先比较帧类型，再比较tag值
+*/
+func compareTag(a *TLVPkg, b *TLVPkg) int {
+	if a.FrameType != b.FrameType {
+		return int(a.FrameType) - int(b.FrameType)
+	}
+	return a.TagValue - b.TagValue
+}
+
+/**
+按规范顺序排列的子节点，tag相同的节点保持原有顺序，不修改原切片
+*/
+func canonicalNode(node []*TLVObject) []*TLVObject {
+	sorted := make([]*TLVObject, len(node))
+	copy(sorted, node)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return compareTag(&sorted[i].Pkg, &sorted[j].Pkg) < 0
+	})
+	return sorted
+}
+
+/**
+检查最后解析出的子节点是否符合规范顺序
+*/
+func checkCanonicalOrder(node []*TLVObject) error {
+	count := len(node)
+	if count < 2 || compareTag(&node[count-2].Pkg, &node[count-1].Pkg) <= 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: tag %d 出现在 tag %d 之后", ErrNonCanonical, node[count-1].Pkg.TagValue, node[count-2].Pkg.TagValue)
+}
+
+// BER规则不定长方式的长度字节
+var berIndefiniteLength = []byte{0x80}
+
+/**
+生成BER规则的tag字节数据
+tag值不小于0x1f时首字节0~4位全部置1，后续字节按大端顺序每字节存放7bit，第7位表示是否还有后续字节
+*/
+func buildBERTag(frameType byte, dataType byte, tagValue int) (tagBytes []byte) {
+	if tagValue < 0x1f {
+		return []byte{frameType | dataType | byte(tagValue)}
+	}
+
+	tagBytes = append(tagBytes, frameType|dataType|0x1f)
+	return appendBase128(tagBytes, tagValue)
+}
+
+// 按大端顺序写入base-128编码的数值
+func appendBase128(buf []byte, value int) []byte {
+	digitCount := 1
+	for remain := value >> 7; remain > 0; remain >>= 7 {
+		digitCount++
+	}
+
+	for i := digitCount - 1; i >= 0; i-- {
+		digit := byte(value>>(uint(i)*7)) & 0x7f
+		if i > 0 {
+			digit |= 0x80
+		}
+		buf = append(buf, digit)
+	}
+	return buf
+}
+
+/**
+生成BER规则的长度字节数据
+小于128时使用短形式，否则首字节为0x8N，后接N字节大端长度
+*/
+func buildBERLength(length int) (lenBytes []byte) {
+	if length < 0x80 {
+		return []byte{byte(length)}
+	}
+
+	byteCount := 0
+	for remain := length; remain > 0; remain >>= 8 {
+		byteCount++
+	}
+
+	lenBytes = make([]byte, 1+byteCount)
+	lenBytes[0] = 0x80 | byte(byteCount)
+	for i := byteCount; i > 0; i-- {
+		lenBytes[i] = byte(length)
+		length >>= 8
+	}
+	return lenBytes
+}
+
+/**
+查找BER规则的tag部分占多少字节，数据不完整时返回0
+*/
+func berTagByteCount(tlvBytes []byte) int {
+	if len(tlvBytes) == 0 {
+		return 0
+	}
+
+	if tlvBytes[0]&0x1f != 0x1f {
+		return 1
+	}
+
+	for i := 1; i < len(tlvBytes); i++ {
+		if tlvBytes[i]&0x80 == 0 {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+/**
+查找BER规则的length部分占多少字节，数据不完整时返回0
+*/
+func berLenByteCount(lenBytes []byte) int {
+	if len(lenBytes) == 0 {
+		return 0
+	}
+
+	//短形式及不定长方式只占一个字节
+	if lenBytes[0] <= 0x80 {
+		return 1
+	}
+
+	byteCount := 1 + int(lenBytes[0]&0x7f)
+	if byteCount > len(lenBytes) {
+		return 0
+	}
+	return byteCount
+}
+
+/**
+解析BER规则的数据类型
+*/
+func parseBERTag(tagBytes []byte) (frameType byte, dataType byte, tagValue int) {
+	frameType = tagBytes[0] & 0xc0
+	dataType = tagBytes[0] & DataTypeStruct
+
+	if len(tagBytes) == 1 {
+		return frameType, dataType, int(tagBytes[0] & 0x1f)
+	}
+
+	for i := 1; i < len(tagBytes); i++ {
+		tagValue = tagValue<<7 | int(tagBytes[i]&0x7f)
+	}
+	return frameType, dataType, tagValue
+}
+
+/**
+解析BER规则的数据长度
+*/
+func parseBERLength(lenBytes []byte) (length int, indefinite bool, err error) {
+	if lenBytes[0] < 0x80 {
+		return int(lenBytes[0]), false, nil
+	}
+
+	if lenBytes[0] == 0x80 {
+		return 0, true, nil
+	}
+
+	for i := 1; i < len(lenBytes); i++ {
+		if length > math.MaxInt32>>8 {
+			return 0, false, ErrLengthOverflow
+		}
+		length = length<<8 | int(lenBytes[i])
+	}
+	return length, false, nil
+}
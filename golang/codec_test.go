@@ -0,0 +1,283 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golang
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+/**
+测试BER规则的tag及长度编码是否与文档一致
+*/
+func TestBERProfile(t *testing.T) {
+	tagBytes := buildBERTag(FarmeTypePrivate, DataTypeStruct, 131071)
+	if !bytes.Equal(tagBytes, []byte{0x7f, 0x87, 0xff, 0x7f}) {
+		t.Errorf("tagBytes = %x", tagBytes)
+	}
+	frameType, dataType, tagValue := parseBERTag(tagBytes)
+	if frameType != FarmeTypePrivate || dataType != DataTypeStruct || tagValue != 131071 {
+		t.Errorf("frameType = %d, dataType = %d, tagValue = %d", frameType, dataType, tagValue)
+	}
+
+	rawLength := []int{0x00, 0x7f, 0x80, 234, 0xffff, 0x10000}
+	for i := 0; i < len(rawLength); i++ {
+		lenBytes := buildBERLength(rawLength[i])
+		length, indefinite, err := parseBERLength(lenBytes)
+		if err != nil || indefinite || length != rawLength[i] || berLenByteCount(lenBytes) != len(lenBytes) {
+			t.Errorf("rawLength[%d] = %d, lenBytes = %x, length = %d", i, rawLength[i], lenBytes, length)
+		}
+	}
+	if lenBytes := buildBERLength(234); !bytes.Equal(lenBytes, []byte{0x81, 0xea}) {
+		t.Errorf("lenBytes = %x", lenBytes)
+	}
+
+	if _, _, err := parseBERLength([]byte{0x89, 1, 0, 0, 0, 0, 0, 0, 0, 0}); err != ErrLengthOverflow {
+		t.Errorf("err = %v", err)
+	}
+}
+
+/**
+测试按BER规则编码的数据能被解码器及FromBytesWith还原
+*/
+func TestBERRoundTrip(t *testing.T) {
+	codec := Codec{Profile: ProfileBER}
+
+	tlvBuilder := TLVObject{}
+	outer := TLVObject{}
+	tlvBuilder.Put(40, &outer)
+	outer.PutString(1, string(make([]byte, 300)))
+	inner := TLVObject{}
+	outer.PutIndefinite(2, &inner)
+	inner.PutInt64(100, -1)
+
+	tlvBytes, err := tlvBuilder.BytesWith(codec)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if tlvBytes[0] != 0x3f || tlvBytes[1] != 40 || tlvBytes[2] != 0x82 {
+		t.Fatalf("tlvBytes = %x", tlvBytes[:4])
+	}
+
+	check := func(tlvObject *TLVObject) {
+		outerParse, ok := tlvObject.Get(40)
+		if ok == false {
+			t.Fatalf("没有找到outer")
+		}
+		if stringValue, _ := outerParse.GetString(1); len(stringValue) != 300 {
+			t.Errorf("len(stringValue) = %d", len(stringValue))
+		}
+		innerParse, ok := outerParse.Get(2)
+		if ok == false || innerParse.Pkg.Indefinite == false {
+			t.Fatalf("没有找到不定长的inner")
+		}
+		if int64Value, _ := innerParse.GetInt64(100); int64Value != -1 {
+			t.Errorf("int64Value = %d", int64Value)
+		}
+	}
+
+	tlvParser := TLVObject{}
+	if err := tlvParser.FromBytesWith(tlvBytes, codec); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	check(&tlvParser)
+
+	decoder := Decoder{Codec: codec}
+	var tlvArray []TLVObject
+	for i := 0; i < len(tlvBytes); i += 7 {
+		end := i + 7
+		if end > len(tlvBytes) {
+			end = len(tlvBytes)
+		}
+		objs, err := decoder.Parse(tlvBytes[i:end], end-i)
+		if err != nil {
+			t.Fatalf("err = %v", err)
+		}
+		tlvArray = append(tlvArray, objs...)
+	}
+	if len(tlvArray) != 1 {
+		t.Fatalf("len(tlvArray) = %d", len(tlvArray))
+	}
+	check(&tlvArray[0])
+
+	//按旧规则编码的数据不会被误认为BER数据
+	legacyBytes, _ := tlvBuilder.BytesWith(Codec{})
+	if bytes.Equal(legacyBytes, tlvBytes) {
+		t.Errorf("两种规则的编码结果相同")
+	}
+}
+
+/**
+测试规范形式的编码结果与子节点的添加顺序无关
+*/
+func TestCanonicalEncoding(t *testing.T) {
+	build := func(reverse bool) *TLVObject {
+		tlvBuilder := &TLVObject{}
+		tlvObject := TLVObject{}
+		tlvBuilder.PutIndefinite(1, &tlvObject)
+		if reverse {
+			tlvObject.PutString(40, "b")
+			tlvObject.PutInt32(3, 7)
+			tlvObject.PutString(40, "c")
+		} else {
+			tlvObject.PutInt32(3, 7)
+			tlvObject.PutString(40, "b")
+			tlvObject.PutString(40, "c")
+		}
+		tlvBuilder.PutBool(0, true)
+		return tlvBuilder
+	}
+
+	for _, profile := range []Profile{ProfileLegacy, ProfileBER} {
+		codec := Codec{Profile: profile, Canonical: true}
+		a, _ := build(false).BytesWith(codec)
+		b, _ := build(true).BytesWith(codec)
+		if !bytes.Equal(a, b) {
+			t.Errorf("profile = %d, a = %x, b = %x", profile, a, b)
+		}
+
+		tlvParser := TLVObject{}
+		if err := tlvParser.FromBytesWith(a, Codec{Profile: profile, Strict: true}); err != nil {
+			t.Errorf("profile = %d, err = %v", profile, err)
+		}
+		if tlvParser.node[0].Pkg.TagValue != 0 || tlvParser.node[1].Pkg.Indefinite {
+			t.Errorf("profile = %d, tlvParser = %v", profile, tlvParser)
+		}
+	}
+}
+
+/**
+测试严格模式拒绝非规范形式的数据
+*/
+func TestStrictDecode(t *testing.T) {
+	cases := []struct {
+		profile  Profile
+		tlvBytes []byte
+	}{
+		{ProfileLegacy, []byte{0x01, 0x81, 0x00, 0x00}},                      // 长度varint末尾为0
+		{ProfileLegacy, []byte{0x80, 0x05, 0x00}},                            // tag小于0x1f却使用扩展字节
+		{ProfileLegacy, []byte{0x21, 0x80, 0x00, 0x00, 0x00}},                // 不定长方式
+		{ProfileLegacy, []byte{0x02, 0x00, 0x01, 0x00}},                      // 子节点顺序错误
+		{ProfileBER, []byte{0x01, 0x81, 0x01, 0x00}},                         // 长度小于128却使用长形式
+		{ProfileBER, []byte{0x01, 0x82, 0x00, 0x01, 0x00}},                   // 长形式前导0
+		{ProfileBER, []byte{0x1f, 0x05, 0x00}},                               // tag小于0x1f却使用扩展字节
+		{ProfileBER, []byte{0x1f, 0x80, 0x20, 0x00}},                         // tag扩展字节前导0
+		{ProfileBER, []byte{0x21, 0x80, 0x00, 0x00}},                         // 不定长方式
+		{ProfileBER, []byte{0x21, 0x04, 0x02, 0x00, 0x01, 0x00, 0x00, 0x00}}, // 嵌套的子节点顺序错误
+	}
+
+	for i, c := range cases {
+		tlvObject := TLVObject{}
+		if err := tlvObject.FromBytesWith(c.tlvBytes, Codec{Profile: c.profile}); err != nil {
+			t.Errorf("cases[%d] 非严格模式解码失败: %v", i, err)
+		}
+
+		tlvObject = TLVObject{}
+		err := tlvObject.FromBytesWith(c.tlvBytes, Codec{Profile: c.profile, Strict: true})
+		if errors.Is(err, ErrNonCanonical) == false {
+			t.Errorf("cases[%d] err = %v", i, err)
+		}
+	}
+}
+
+// 测试四种帧类型的编解码及按帧类型查找
+func TestClassTag(t *testing.T) {
+	classes := []Class{ClassUniversal, ClassApplication, ClassContext, ClassPrivate}
+
+	tlvBuilder := TLVObject{}
+	tlvObject := TLVObject{}
+	tlvBuilder.Put(ClassTag(ClassContext, 33), &tlvObject)
+	for i, class := range classes {
+		tlvObject.PutInt32(ClassTag(class, 5), int32(i))
+	}
+
+	if _, err := tlvBuilder.BytesWith(Codec{}); err != ErrInvalidClass {
+		t.Errorf("旧规则不应支持ClassContext, err = %v", err)
+	}
+	if tlvBytes := tlvBuilder.Bytes(); tlvBytes != nil {
+		t.Errorf("编码失败时Bytes应返回nil, tlvBytes = %x", tlvBytes)
+	}
+
+	tlvBytes, err := tlvBuilder.BytesWith(Codec{Profile: ProfileBER})
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if tlvBytes[0] != 0xbf {
+		t.Errorf("tlvBytes[0] = %x", tlvBytes[0])
+	}
+
+	tlvParser := TLVObject{}
+	if err := tlvParser.FromBytesWith(tlvBytes, Codec{Profile: ProfileBER}); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if _, ok := tlvParser.Get(ClassTag(ClassUniversal, 33)); ok {
+		t.Errorf("不应找到ClassUniversal的节点")
+	}
+	findObject, ok := tlvParser.Get(ClassTag(ClassContext, 33))
+	if ok == false || findObject.Pkg.FrameType != ClassContext {
+		t.Fatalf("没有找到ClassContext的节点")
+	}
+	for i, class := range classes {
+		if int32Value, ok := findObject.GetInt32(ClassTag(class, 5)); ok == false || int32Value != int32(i) {
+			t.Errorf("class = %x, int32Value = %d", class, int32Value)
+		}
+	}
+	if int32Value, _ := findObject.GetInt32(5); int32Value != 0 {
+		t.Errorf("不带帧类型的key应返回第一个节点, int32Value = %d", int32Value)
+	}
+}
+
+// 测试超出范围的tag及无效的key
+func TestClassTagRange(t *testing.T) {
+	if key := ClassTag(ClassContext, maxClassTagValue); key >= 0 {
+		t.Errorf("带帧类型的key应为负数, key = %d", key)
+	}
+	if class, tagValue, qualified := splitKey(ClassTag(ClassPrivate, maxClassTagValue)); class != ClassPrivate ||
+		tagValue != maxClassTagValue || qualified == false {
+		t.Errorf("class = %x, tagValue = %d, qualified = %v", class, tagValue, qualified)
+	}
+
+	tlvObject := TLVObject{}
+	invalidKeys := []int{ClassTag(ClassContext, maxClassTagValue+1), ClassTag(ClassUniversal, -1), ClassTag(0x41, 1)}
+	for i, key := range invalidKeys {
+		if err := tlvObject.PutInt32(key, 1); errors.Is(err, ErrInvalidParam) == false {
+			t.Errorf("invalidKeys[%d] err = %v", i, err)
+		}
+	}
+
+	//不带帧类型的key即tag值，不会被当作带帧类型的key
+	overflowKeys := []int{maxClassTagValue + 1, 1<<30 | 5}
+	for i, key := range overflowKeys {
+		if err := tlvObject.PutInt32(key, 1); errors.Is(err, ErrTagOverflow) == false {
+			t.Errorf("overflowKeys[%d] PutInt32 err = %v", i, err)
+		}
+		if err := tlvObject.Put(key, nil); errors.Is(err, ErrTagOverflow) == false {
+			t.Errorf("overflowKeys[%d] Put err = %v", i, err)
+		}
+		if err := tlvObject.PutNull(key); errors.Is(err, ErrTagOverflow) == false {
+			t.Errorf("overflowKeys[%d] PutNull err = %v", i, err)
+		}
+		if err := NewEncoder(&bytes.Buffer{}).WriteField(key, nil); errors.Is(err, ErrTagOverflow) == false {
+			t.Errorf("overflowKeys[%d] WriteField err = %v", i, err)
+		}
+	}
+	if tlvObject.Len() != 0 {
+		t.Errorf("无效的key不应添加节点, Len = %d", tlvObject.Len())
+	}
+	if _, ok := tlvObject.Get(invalidKeys[0]); ok {
+		t.Errorf("无效的key不应匹配任何节点")
+	}
+}
@@ -17,9 +17,18 @@ package golang
 
 import (
 	"errors"
-	"fmt"
+	"io"
 )
 
+// tag及length字段允许的最大字节数
+const (
+	maxTagByteCount = 5
+	maxLenByteCount = 9
+)
+
+// 从io.Reader中每次读取的字节数
+const decodeReadSize = 4096
+
 // TLV网络数据解码器
 type Decoder struct {
 	buf    []byte // 缓冲区
@@ -28,22 +37,89 @@ type Decoder struct {
 	beforeCursor int // 之前解析到数据位置
 	curCursor    int // 当前解析到数据位置
 
-	isFindTag bool
-	isFindLen bool
+	isFindTag    bool
+	isFindLen    bool
+	isIndefinite bool // 当前数据包是否为不定长方式
 
 	valueLen int // 数据段的长度
+	consumed int // 已经解析完并丢弃的数据长度，用于计算错误发生的位置
+
+	scanCursor int // 不定长方式时下一个待解析的子包位置，数据不完整时从这里继续
+	depth      int // 不定长方式时尚未遇到结束标记的嵌套层数，为0表示还未开始扫描
+
+	Codec Codec // 编码规则
+
+	reader  io.Reader    // 数据来源，由NewDecoder设置
+	readBuf []byte       // 读取数据的缓冲区
+	pending []*TLVObject // 已经解析出但还未返回的对象
+	readErr error        // 读取或解析时发生的错误，在pending返回完后返回
+}
+
+// 创建从io.Reader中读取TLV数据的解码器
+// 不使用NewDecoder创建的解码器需要通过Parse自行传入数据
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{reader: r}
+}
+
+// 从io.Reader中读取下一个TLV对象，返回的对象与FromBytes得到的对象结构相同
+// 数据正常结束时返回io.EOF，在TLV包中间结束时返回io.ErrUnexpectedEOF
+func (this *Decoder) Decode() (*TLVObject, error) {
+	if this.reader == nil {
+		return nil, ErrInvalidParam
+	}
+
+	for len(this.pending) == 0 {
+		if this.readErr != nil {
+			return nil, this.readErr
+		}
+
+		if this.readBuf == nil {
+			this.readBuf = make([]byte, decodeReadSize)
+		}
+
+		n, err := this.reader.Read(this.readBuf)
+		if n > 0 {
+			tlvArray, parseErr := this.parse(this.readBuf, n)
+			this.pending = append(this.pending, tlvArray...)
+			if parseErr != nil {
+				err = parseErr
+			}
+		}
+
+		if err == io.EOF && this.bufLen > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		this.readErr = err
+	}
+
+	tlvObject := this.pending[0]
+	this.pending[0] = nil
+	this.pending = this.pending[1:]
+	return tlvObject, nil
 }
 
 /**
 从网络流数据中解析出TLV结构数据
+返回的对象应通过下标原位使用(如&tlvArray[i])，再次按值复制后修改子节点不会使复制对象的编码缓存失效
 */
 func (this *Decoder) Parse(request []byte, requestLen int) (tlvArray []TLVObject, err error) {
-
-	defer func() {
-		if errPanic := recover(); errPanic != nil {
-			err = errors.New(fmt.Sprintf("tlv parse panic: %v", errPanic))
+	objects, err := this.parse(request, requestLen)
+	if len(objects) > 0 {
+		tlvArray = make([]TLVObject, len(objects))
+		for i, object := range objects {
+			tlvArray[i] = *object
+			tlvArray[i].adoptChildren()
 		}
-	}()
+	}
+	return tlvArray, err
+}
+
+// 解析传入的数据，返回已经完整的TLV对象
+func (this *Decoder) parse(request []byte, requestLen int) (tlvArray []*TLVObject, err error) {
+
+	if requestLen < 0 || requestLen > len(request) {
+		return nil, ErrInvalidParam
+	}
 
 	this.buf = append(this.buf, request[:requestLen]...)
 	this.bufLen += requestLen
@@ -56,27 +132,54 @@ func (this *Decoder) Parse(request []byte, requestLen int) (tlvArray []TLVObject
 
 		//计算tag
 		if this.isFindTag == false {
-			if this.buf[this.curCursor]&0x80 == 0 {
+			if this.Codec.tagByteCount(this.buf[:this.curCursor+1]) > 0 {
 				this.isFindTag = true
 				this.beforeCursor = this.curCursor + 1
 
 				//fmt.Printf("findTag curCursor = %v, tag = %v\n", this.curCursor, this.buf[this.curCursor]&0x1f)
+			} else if this.curCursor+1 >= maxTagByteCount {
+				return tlvArray, this.streamError(ErrTagOverflow)
 			}
 			continue
 		}
 
 		//计算length
 		if this.isFindLen == false {
-			if this.buf[this.curCursor]&0x80 == 0 {
+			lenBytes := this.buf[this.beforeCursor : this.curCursor+1]
+			if this.Codec.lenByteCount(lenBytes) > 0 {
 				this.isFindLen = true
-				this.valueLen = parseLength(this.buf[this.beforeCursor : this.curCursor+1])
+				this.valueLen, this.isIndefinite, err = this.Codec.parseLength(lenBytes)
+				if err != nil {
+					return tlvArray, this.streamError(err)
+				}
 
 				this.beforeCursor = this.curCursor
 
 				//fmt.Printf("findLen curCursor = %v, valueLen = %v\n", this.curCursor, this.valueLen)
-				if this.valueLen == 0 {
-					tlvArray = this.addParsedObj(tlvArray)
+				if this.isIndefinite == false && this.valueLen == 0 {
+					if tlvArray, err = this.addParsedObj(tlvArray); err != nil {
+						return tlvArray, err
+					}
 				}
+			} else if len(lenBytes) >= maxLenByteCount {
+				return tlvArray, this.streamError(ErrLengthOverflow)
+			}
+			continue
+		}
+
+		//不定长方式需要找到与之匹配的结束标记，数据不完整时等待后续数据
+		if this.isIndefinite {
+			ok, err := this.scanIndefinite()
+			if err != nil {
+				return tlvArray, this.streamError(err)
+			}
+			if ok == false {
+				break
+			}
+
+			this.curCursor = this.scanCursor - 1
+			if tlvArray, err = this.addParsedObj(tlvArray); err != nil {
+				return tlvArray, err
 			}
 			continue
 		}
@@ -86,65 +189,129 @@ func (this *Decoder) Parse(request []byte, requestLen int) (tlvArray []TLVObject
 		if this.curCursor-this.beforeCursor == this.valueLen {
 			//已经完整的获取到一个tlv包数据，开始解析整个tlv包
 			//fmt.Printf("find a TLV object: curCursor = %d, beforeCursor = %d\n", this.curCursor, this.beforeCursor)
-			tlvArray = this.addParsedObj(tlvArray)
+			if tlvArray, err = this.addParsedObj(tlvArray); err != nil {
+				return tlvArray, err
+			}
 		}
 	}
 
 	return tlvArray, nil
 }
 
+/**
+查找不定长方式的结束位置，结果为scanCursor，数据不完整时ok为false
+已经扫描过的子包不再重复解析，定长的子包只解析tag及length后整体跳过
+*/
+func (this *Decoder) scanIndefinite() (ok bool, err error) {
+	if this.depth == 0 {
+		this.scanCursor = this.beforeCursor + 1
+		this.depth = 1
+	}
+
+	for this.depth > 0 {
+		remain := this.buf[this.scanCursor:this.bufLen]
+		if len(remain) < len(endOfContents) {
+			return false, nil
+		}
+		if startsWithEndOfContents(remain) {
+			this.scanCursor += len(endOfContents)
+			this.depth--
+			continue
+		}
+
+		pkg := TLVPkg{}
+		ok, err = this.Codec.parseTagLength(remain, &pkg)
+		if ok == false || err != nil {
+			return false, err
+		}
+		if pkg.Indefinite && pkg.DataType == DataTypeStruct {
+			this.scanCursor += pkg.tagByteCount + pkg.lenByteCount
+			this.depth++
+			continue
+		}
+		this.scanCursor += pkg.Size()
+	}
+
+	return true, nil
+}
+
 // 添加解析完成了的对象
-func (this *Decoder) addParsedObj(tlvArray []TLVObject) (retArray []TLVObject) {
-	tlvObject := TLVObject{}
-	tlvObject.FromBytes(this.buf[:this.curCursor+1])
+func (this *Decoder) addParsedObj(tlvArray []*TLVObject) (retArray []*TLVObject, err error) {
+	tlvObject := &TLVObject{}
+	err = tlvObject.FromBytesWith(this.buf[:this.curCursor+1], this.Codec)
+	if err != nil {
+		return tlvArray, this.streamError(err)
+	}
 
 	retArray = append(tlvArray, tlvObject)
 	this.reset()
 
-	return retArray
+	return retArray, nil
+}
+
+// 将错误的偏移转换为在整个数据流中的位置
+func (this *Decoder) streamError(err error) error {
+	var tlvErr *Error
+	if errors.As(err, &tlvErr) {
+		tlvErr.Offset += this.consumed
+		return tlvErr
+	}
+	return newError(err, this.consumed, nil)
 }
 
 /**
 解析完一个TLV结构后，重置解码器
 */
 func (this *Decoder) reset() {
+	this.consumed += this.curCursor + 1
+
 	//遗弃已经解析完的数据包
 	this.buf = this.buf[this.curCursor+1:]
 	this.bufLen = this.bufLen - this.curCursor - 1
 
 	this.isFindLen = false
 	this.isFindTag = false
+	this.isIndefinite = false
+	this.scanCursor = 0
+	this.depth = 0
 
 	this.beforeCursor = 0
 	this.curCursor = -1
 }
 
 /**
-查找tag部分占多少字节
+查找tag部分占多少字节，数据不完整时返回0
 */
 func findTagByteCount(tlvBytes []byte) (tagByteCount int) {
 	for i := 0; i < len(tlvBytes); i++ {
-		tagByteCount++
 		if tlvBytes[i]&0x80 == 0 {
-			break
+			return i + 1
 		}
 	}
 
-	return tagByteCount
+	return 0
 }
 
 /**
-查找length部分占多少字节
+查找length部分占多少字节，数据不完整时返回0
 */
 func findLenByteCount(tlvBytes []byte, lenStartPos int) (lenByteCount int) {
 	for i := lenStartPos; i < len(tlvBytes); i++ {
-		lenByteCount++
 		if tlvBytes[i]&0x80 == 0 {
-			break
+			return i - lenStartPos + 1
 		}
 	}
 
-	return lenByteCount
+	return 0
+}
+
+/**
+判断长度字段是否为不定长方式
+*/
+func isIndefiniteLength(lenBytes []byte) bool {
+	return len(lenBytes) == len(indefiniteLength) &&
+		lenBytes[0] == indefiniteLength[0] &&
+		lenBytes[1] == indefiniteLength[1]
 }
 
 /**
@@ -0,0 +1,170 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golang
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strconv"
+	"testing"
+	"testing/iotest"
+)
+
+// 测试从io.Reader中逐个读取TLV对象
+func TestDecoderDecode(t *testing.T) {
+	var stream []byte
+	for i := 0; i < 3; i++ {
+		tlvBuilder := TLVObject{}
+		tlvObject := TLVObject{}
+		tlvBuilder.PutIndefinite(i, &tlvObject)
+		tlvObject.PutInt32(0, int32(i))
+		tlvObject.PutString(1, string(make([]byte, 200)))
+		stream = append(stream, tlvBuilder.Bytes()...)
+	}
+
+	readers := []io.Reader{
+		bytes.NewReader(stream),
+		iotest.OneByteReader(bytes.NewReader(stream)),
+		bufio.NewReader(iotest.HalfReader(bytes.NewReader(stream))),
+	}
+	for _, r := range readers {
+		decoder := NewDecoder(r)
+		for i := 0; i < 3; i++ {
+			tlvObject, err := decoder.Decode()
+			if err != nil {
+				t.Fatalf("i = %d, err = %v", i, err)
+			}
+			findObject, ok := tlvObject.Get(i)
+			if ok == false {
+				t.Fatalf("没有找到tag %d", i)
+			}
+			if int32Value, _ := findObject.GetInt32(0); int32Value != int32(i) {
+				t.Errorf("int32Value = %d", int32Value)
+			}
+		}
+		if _, err := decoder.Decode(); err != io.EOF {
+			t.Errorf("err = %v", err)
+		}
+	}
+
+	decoder := NewDecoder(bytes.NewReader(stream[:len(stream)-1]))
+	for i := 0; i < 2; i++ {
+		if _, err := decoder.Decode(); err != nil {
+			t.Fatalf("err = %v", err)
+		}
+	}
+	if _, err := decoder.Decode(); err != io.ErrUnexpectedEOF {
+		t.Errorf("err = %v", err)
+	}
+}
+
+// 解码得到的对象是子节点的parent，修改子节点后对象的缓存失效
+func TestDecoderResultOwnsChildren(t *testing.T) {
+	tlvBuilder := TLVObject{}
+	child := &TLVObject{}
+	child.PutInt32(0, 1)
+	tlvBuilder.Put(1, child)
+	data := tlvBuilder.Bytes()
+
+	tlvObject, err := NewDecoder(bytes.NewReader(data)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tlvArray, err := (&Decoder{}).Parse(data, len(data))
+	if err != nil || len(tlvArray) != 1 {
+		t.Fatalf("len = %d, err = %v", len(tlvArray), err)
+	}
+
+	for i, tlvObject := range []*TLVObject{tlvObject, &tlvArray[0]} {
+		if tlvObject.ownsChildren() == false {
+			t.Errorf("objects[%d]的子节点没有指向该对象", i)
+		}
+		tlvObject.Bytes()
+		found, _ := tlvObject.Get(1)
+		found.SetInt32(0, 2)
+		if tlvObject.cache != nil {
+			t.Errorf("objects[%d]修改子节点后缓存没有失效", i)
+		}
+		tlvParser := TLVObject{}
+		tlvParser.FromBytes(tlvObject.Bytes())
+		if v, _ := tlvParser.GetPathInt32("1/0"); v != 2 {
+			t.Errorf("objects[%d] v = %d", i, v)
+		}
+	}
+}
+
+// 深层嵌套的不定长数据逐字节到达时，已经扫描过的子包不重复解析
+func TestDecoderDeepIndefinite(t *testing.T) {
+	data := deepIndefinite(1000)
+
+	tlvObject, err := NewDecoder(iotest.OneByteReader(bytes.NewReader(data))).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := tlvObject
+	for i := 0; i < 1000; i++ {
+		var ok bool
+		if node, ok = node.Get(1); ok == false {
+			t.Fatalf("第%d层没有找到tag 1", i)
+		}
+	}
+	if v, _ := node.GetInt32(0); v != 7 {
+		t.Errorf("v = %d", v)
+	}
+	if bytes.Equal(tlvObject.Bytes(), data) == false {
+		t.Errorf("重新编码的结果与原始数据不一致")
+	}
+
+	if _, err = NewDecoder(bytes.NewReader(data[:len(data)-1])).Decode(); err != io.ErrUnexpectedEOF {
+		t.Errorf("err = %v", err)
+	}
+}
+
+// 按每次4字节的方式解码深层嵌套的不定长数据，耗时应与数据长度成线性关系
+func BenchmarkDecodeDeepIndefinite(b *testing.B) {
+	for _, depth := range []int{1000, 4000} {
+		data := deepIndefinite(depth)
+		b.Run(strconv.Itoa(depth), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				decoder := Decoder{}
+				for offset := 0; offset < len(data); offset += 4 {
+					end := offset + 4
+					if end > len(data) {
+						end = len(data)
+					}
+					if _, err := decoder.Parse(data[offset:end], end-offset); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+// 生成depth层以不定长方式嵌套的tag 1，最内层为int32节点
+func deepIndefinite(depth int) []byte {
+	empty := TLVObject{}
+	empty.PutIndefinite(1, &TLVObject{})
+	head := empty.Bytes()
+	head = head[:len(head)-len(endOfContents)]
+
+	leaf := TLVObject{}
+	leaf.PutInt32(0, 7)
+
+	data := bytes.Repeat(head, depth)
+	data = append(data, leaf.Bytes()...)
+	return append(data, bytes.Repeat(endOfContents, depth)...)
+}
@@ -0,0 +1,205 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// 实现TLVObject的比较功能
+package golang
+
+// 比较TLVObject时的选项，零值要求子节点顺序及整数的字节数都相同
+type CompareOptions struct {
+	// 忽略tag不同的子节点之间的顺序，tag相同的子节点(重复字段)仍按顺序比较
+	IgnoreOrder bool
+
+	// 1/2/4/8字节的整数按数值比较，如PutInt8(1, 5)与PutInt32(1, 5)相等
+	// 字节数不同时默认按有符号整数扩展后比较，此时PutUint8(1, 255)与PutInt32(1, -1)相等
+	IgnoreIntWidth bool
+
+	// 与IgnoreIntWidth一起使用，按无符号整数扩展后比较，此时PutUint8(1, 255)与PutUint32(1, 255)相等
+	UnsignedInt bool
+}
+
+// 差异的类型
+type ChangeType int
+
+const (
+	ChangeAdded     ChangeType = iota // b中新增的节点
+	ChangeRemoved                     // a中被删除的节点
+	ChangeModified                    // 值或数据类型发生变化的节点
+	ChangeReordered                   // 子节点的顺序发生变化，仅在不忽略顺序时报告
+)
+
+var changeTypeNames = [...]string{"added", "removed", "modified", "reordered"}
+
+func (this ChangeType) String() string {
+	if this >= 0 && int(this) < len(changeTypeNames) {
+		return changeTypeNames[this]
+	}
+	return "unknown"
+}
+
+// 两个TLVObject之间的一处差异
+type Change struct {
+	Path string     // 节点的路径，格式与GetPath相同，可用于在a或b中获取节点，顶层顺序变化时为空
+	Type ChangeType // 差异的类型
+	Old  *TLVObject // a中的节点，ChangeAdded时为nil，ChangeReordered时为父节点
+	New  *TLVObject // b中的节点，ChangeRemoved时为nil，ChangeReordered时为父节点
+}
+
+// 判断两个TLVObject的子节点是否完全相同，只比较数据，不比较编码方式(如是否使用不定长方式)
+// nil视为没有子节点的TLVObject
+func Equal(a *TLVObject, b *TLVObject) bool {
+	return EqualWith(a, b, CompareOptions{})
+}
+
+// 按指定的选项判断两个TLVObject的子节点是否相同
+func EqualWith(a *TLVObject, b *TLVObject, options CompareOptions) bool {
+	return len(DiffWith(a, b, options)) == 0
+}
+
+// 比较两个TLVObject，返回b相对于a的差异
+func Diff(a *TLVObject, b *TLVObject) []Change {
+	return DiffWith(a, b, CompareOptions{})
+}
+
+// 按指定的选项比较两个TLVObject，返回b相对于a的差异
+//
+// 子节点按帧类型及tag分组，同一组内按顺序一一对应：对应的TLV嵌套结构递归比较，
+// 基本数据不同时为ChangeModified，多出的节点为ChangeAdded或ChangeRemoved。
+// 不忽略顺序时，各组都相同但子节点的排列不同的父节点报告为ChangeReordered
+func DiffWith(a *TLVObject, b *TLVObject, options CompareOptions) []Change {
+	if a == nil {
+		a = &TLVObject{}
+	}
+	if b == nil {
+		b = &TLVObject{}
+	}
+	differ := differ{options: options}
+	differ.diffChildren("", a, b)
+	return differ.changes
+}
+
+type differ struct {
+	options CompareOptions
+	changes []Change
+}
+
+func (this *differ) add(path string, changeType ChangeType, oldNode *TLVObject, newNode *TLVObject) {
+	this.changes = append(this.changes, Change{Path: path, Type: changeType, Old: oldNode, New: newNode})
+}
+
+// 比较a及b的子节点，prefix为a及b的路径
+func (this *differ) diffChildren(prefix string, a *TLVObject, b *TLVObject) {
+	groupA, keys := groupChildren(a.node)
+	groupB, keysB := groupChildren(b.node)
+	for _, key := range keysB {
+		if _, ok := groupA[key]; ok == false {
+			keys = append(keys, key)
+		}
+	}
+
+	count := len(this.changes)
+	for _, key := range keys {
+		nodesA, nodesB := groupA[key], groupB[key]
+		for i := 0; i < len(nodesA) || i < len(nodesB); i++ {
+			var path string
+			if i < len(nodesA) {
+				path = prefix + childPath(a, nodesA[i])
+			} else {
+				path = prefix + childPath(b, nodesB[i])
+			}
+
+			switch {
+			case i >= len(nodesA):
+				this.add(path, ChangeAdded, nil, nodesB[i])
+			case i >= len(nodesB):
+				this.add(path, ChangeRemoved, nodesA[i], nil)
+			default:
+				this.diffNode(path, nodesA[i], nodesB[i])
+			}
+		}
+	}
+
+	if this.options.IgnoreOrder || len(this.changes) != count {
+		return
+	}
+	for i := range a.node {
+		if compareTag(&a.node[i].Pkg, &b.node[i].Pkg) != 0 {
+			path := prefix
+			if path != "" {
+				path = path[:len(path)-1]
+			}
+			this.add(path, ChangeReordered, a, b)
+			return
+		}
+	}
+}
+
+// 比较位置对应的两个节点
+func (this *differ) diffNode(path string, a *TLVObject, b *TLVObject) {
+	if a.Pkg.DataType != b.Pkg.DataType || a.Pkg.IsNull() != b.Pkg.IsNull() {
+		this.add(path, ChangeModified, a, b)
+		return
+	}
+	if a.Pkg.DataType == DataTypeStruct {
+		this.diffChildren(path+"/", a, b)
+		return
+	}
+	if this.valueEqual(a.Pkg.Value, b.Pkg.Value) == false {
+		this.add(path, ChangeModified, a, b)
+	}
+}
+
+// 比较基本数据的数据段
+func (this *differ) valueEqual(a []byte, b []byte) bool {
+	if string(a) == string(b) {
+		return true
+	}
+	if this.options.IgnoreIntWidth == false || len(a) == len(b) || isIntSize(len(a)) == false || isIntSize(len(b)) == false {
+		return false
+	}
+	if this.options.UnsignedInt {
+		return decodeUint(a) == decodeUint(b)
+	}
+	return signExtend(a) == signExtend(b)
+}
+
+func isIntSize(size int) bool {
+	return size == 1 || size == 2 || size == 4 || size == 8
+}
+
+// 将子节点按帧类型及tag分组，keys为各组第一次出现的顺序
+func groupChildren(node []*TLVObject) (groups map[int][]*TLVObject, keys []int) {
+	groups = make(map[int][]*TLVObject)
+	for _, child := range node {
+		key := ClassTag(child.Pkg.FrameType, child.Pkg.TagValue)
+		if _, ok := groups[key]; ok == false {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], child)
+	}
+	return groups, keys
+}
+
+// 子节点在父节点中的路径，序号按GetPath的规则计算
+func childPath(parent *TLVObject, child *TLVObject) string {
+	key := pkgKey(&child.Pkg)
+	index := 0
+	parent.eachMatch(key, func(i int, node *TLVObject) bool {
+		if node == child {
+			return false
+		}
+		index++
+		return true
+	})
+	return formatPathSegment(key, index)
+}
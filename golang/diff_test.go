@@ -0,0 +1,144 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golang
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// 将差异格式化为"类型 路径"的形式
+func formatChanges(changes []Change) []string {
+	ret := make([]string, len(changes))
+	for i, change := range changes {
+		ret[i] = fmt.Sprintf("%v %s", change.Type, change.Path)
+	}
+	return ret
+}
+
+func TestEqual(t *testing.T) {
+	a := TLVObject{}
+	a.PutInt32(1, 5)
+	a.PutString(2, "x")
+	a.PutNull(3)
+
+	b := TLVObject{}
+	b.PutString(2, "x")
+	b.PutInt8(1, 5)
+	b.PutNull(3)
+
+	if Equal(&a, &a) == false {
+		t.Errorf("对象与自身不相等")
+	}
+	if Equal(&a, &b) || EqualWith(&a, &b, CompareOptions{IgnoreOrder: true}) {
+		t.Errorf("整数字节数不同时应不相等")
+	}
+	if EqualWith(&a, &b, CompareOptions{IgnoreOrder: true, IgnoreIntWidth: true}) == false {
+		t.Errorf("忽略顺序及整数字节数后应相等")
+	}
+	if changes := DiffWith(&a, &b, CompareOptions{IgnoreIntWidth: true}); reflect.DeepEqual(formatChanges(changes), []string{"reordered "}) == false {
+		t.Errorf("changes = %v", formatChanges(changes))
+	}
+
+	//解码结果与编码前相等，不比较编码方式
+	c := TLVObject{}
+	c.PutIndefinite(4, &a)
+	tlvParser := TLVObject{}
+	tlvParser.FromBytes(c.Bytes())
+	if Equal(&c, &tlvParser) == false {
+		t.Errorf("解码结果与编码前不相等")
+	}
+
+	//重复字段的顺序不能忽略
+	d, e := TLVObject{}, TLVObject{}
+	d.PutInt32s(1, []int32{1, 2})
+	e.PutInt32s(1, []int32{2, 1})
+	if EqualWith(&d, &e, CompareOptions{IgnoreOrder: true}) {
+		t.Errorf("重复字段的顺序不同时应不相等")
+	}
+
+	//默认按有符号整数扩展，UnsignedInt时按无符号整数扩展
+	f, g, h := TLVObject{}, TLVObject{}, TLVObject{}
+	f.PutUint8(1, 200)
+	g.PutUint16(1, 200)
+	h.PutInt32(1, -56)
+	if EqualWith(&f, &g, CompareOptions{IgnoreIntWidth: true}) || EqualWith(&f, &h, CompareOptions{IgnoreIntWidth: true}) == false {
+		t.Errorf("有符号扩展后0xc8应等于-56")
+	}
+	if EqualWith(&f, &g, CompareOptions{IgnoreIntWidth: true, UnsignedInt: true}) == false ||
+		EqualWith(&f, &h, CompareOptions{IgnoreIntWidth: true, UnsignedInt: true}) {
+		t.Errorf("无符号扩展后0xc8应等于200")
+	}
+
+	//nil视为空对象
+	if Equal(nil, nil) == false || Equal(nil, &TLVObject{}) == false || Equal(&a, nil) {
+		t.Errorf("nil应视为空对象")
+	}
+	if changes := Diff(nil, &a); reflect.DeepEqual(formatChanges(changes), []string{"added 1", "added 2", "added 3"}) == false {
+		t.Errorf("changes = %v", formatChanges(changes))
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := TLVObject{}
+	a.SetPathInt32("1/4/2", 1)
+	a.SetPathInt32("1/4[1]/2", 2)
+	a.SetPathString("1/app:5", "old")
+	a.PutString(3, "removed")
+
+	b := TLVObject{}
+	b.SetPathInt32("1/4/2", 1)
+	b.SetPathInt32("1/4[1]/2", 20)
+	b.SetPathInt32("1/4[2]/2", 3)
+	b.SetPathString("1/app:5", "new")
+	b.PutNull(6)
+
+	changes := Diff(&a, &b)
+	expect := []string{
+		"modified 1/4[1]/2",
+		"added 1/4[2]",
+		"modified 1/app:5",
+		"removed 3",
+		"added 6",
+	}
+	if reflect.DeepEqual(formatChanges(changes), expect) == false {
+		t.Fatalf("changes = %v", formatChanges(changes))
+	}
+
+	//路径可以用于在a或b中获取节点
+	for _, change := range changes {
+		if change.Old != nil {
+			if node, ok := a.GetPath(change.Path); ok == false || node != change.Old {
+				t.Errorf("path = %s, node = %v", change.Path, node)
+			}
+		}
+		if change.New != nil {
+			if node, ok := b.GetPath(change.Path); ok == false || node != change.New {
+				t.Errorf("path = %s, node = %v", change.Path, node)
+			}
+		}
+	}
+	if v, _ := changes[0].New.Pkg.AsInt32(); v != 20 {
+		t.Errorf("v = %v", v)
+	}
+	if s, _ := changes[2].Old.Pkg.AsString(); s != "old" {
+		t.Errorf("s = %q", s)
+	}
+
+	if changes = Diff(&b, &b); len(changes) != 0 {
+		t.Errorf("changes = %v", formatChanges(changes))
+	}
+}
@@ -17,14 +17,27 @@ package golang
 
 import (
 	"fmt"
+	"io"
 )
 
 // 帧类型
+// 旧规则中0x40称为私有类型，在四类模型中对应ClassApplication
 const (
 	FarmeTypePrimitive = 0x00 //基本类型
 	FarmeTypePrivate   = 0x40 //私有类型
 )
 
+// 帧类型，对应tag首字节的第6~7位
+// ProfileLegacy规则中第7位是tag扩展标志，只能使用ClassUniversal及ClassApplication
+type Class byte
+
+const (
+	ClassUniversal   Class = 0x00 //通用类型
+	ClassApplication Class = 0x40 //应用类型
+	ClassContext     Class = 0x80 //上下文相关类型
+	ClassPrivate     Class = 0xc0 //私有类型
+)
+
 // 数据类型
 const (
 	DataTypePrimitive = 0x00 //基本数据编码
@@ -36,29 +49,135 @@ type TLVPkg struct {
 	lenByteCount  int //长度字段占用的字节数
 	dataByteCount int //数据字段暂用的字节数
 
-	FrameType byte   //帧类型，0-基本类型，1-私有类型
+	FrameType Class  //帧类型
 	DataType  byte   //数据类型，0-基本数据，1-TLV数据
 	TagValue  int    //tag类型值
 	Value     []byte //实际数据
 
-	data []byte //数据包字节数据
+	Indefinite bool //是否以不定长方式编码，仅对TLV嵌套数据有效
+	definite   bool //子节点的编码与结束标记相同，即使Indefinite为true也使用定长方式
+
+	data  []byte    //数据包字节数据
+	built pkgFields //生成data时的字段，与当前字段不同时data已过期
+}
+
+// TLVPkg中决定编码结果的字段
+type pkgFields struct {
+	frameType  Class
+	dataType   byte
+	tagValue   int
+	indefinite bool
+	value      []byte
+
+	profile Profile //生成data的编码规则
 }
 
+// 设置数据包字节数据，并记录当前的字段及编码规则
+func (this *TLVPkg) setData(data []byte, profile Profile) {
+	this.data = data
+	this.built = pkgFields{this.FrameType, this.DataType, this.TagValue, this.Indefinite, this.Value, profile}
+}
+
+// 字段在生成data后是否被修改过，Value只比较切片本身，原位修改Value的内容后需要重新Build
+func (this *TLVPkg) stale() bool {
+	built := this.built
+	if built.frameType != this.FrameType || built.dataType != this.DataType ||
+		built.tagValue != this.TagValue || built.indefinite != this.Indefinite {
+		return true
+	}
+	if len(built.value) != len(this.Value) {
+		return true
+	}
+	return len(this.Value) > 0 && &built.value[0] != &this.Value[0]
+}
+
+// 不定长方式的长度字节及结束标记
+// varint长度编码中，128等长度的首字节同样是0x80，因此长度字段写作0x80 0x00，
+// 这一组合不会由buildLength生成
+var (
+	indefiniteLength = []byte{0x80, 0x00}
+	endOfContents    = []byte{0x00, 0x00}
+)
+
 // 构建tlv对象数据
-func (this *TLVPkg) Build() {
-	this.dataByteCount = len(this.Value)
+func (this *TLVPkg) Build() error {
+	return this.BuildWith(Codec{})
+}
+
+// 按指定的编码规则构建tlv对象数据，失败时清空已有的字节数据，Bytes将返回nil
+func (this *TLVPkg) BuildWith(codec Codec) error {
+	this.data = nil
+	if this.TagValue < 0 {
+		return ErrInvalidParam
+	}
+	if this.TagValue > maxClassTagValue {
+		return ErrTagOverflow
+	}
+
+	//null的数据段始终为空
+	value := this.Value
+	if this.IsNull() {
+		value = nil
+	}
+	this.dataByteCount = len(value)
+
+	tagBytes, err := codec.buildTag(this.FrameType, this.DataType, this.TagValue)
+	if err != nil {
+		return err
+	}
 
-	tagBytes := buildTag(this.FrameType, this.DataType, this.TagValue)
-	lenBytes := buildLength(this.dataByteCount)
+	//规范形式不使用不定长方式，null除外
+	indefinite := this.IsNull() || (this.isIndefinite() && codec.Canonical == false)
+
+	var lenBytes []byte
+	if indefinite {
+		lenBytes = codec.indefiniteLength()
+		this.dataByteCount += len(endOfContents)
+	} else {
+		lenBytes = codec.buildLength(this.dataByteCount)
+	}
 
 	//fmt.Printf("dataByteCount = %v, lenBytes = %v\n", this.dataByteCount, lenBytes)
 
 	this.tagByteCount = len(tagBytes)
 	this.lenByteCount = len(lenBytes)
 
-	this.data = append(this.data, tagBytes...)
-	this.data = append(this.data, lenBytes...)
-	this.data = append(this.data, this.Value...)
+	data := make([]byte, 0, this.Size())
+	data = append(data, tagBytes...)
+	data = append(data, lenBytes...)
+	data = append(data, value...)
+	if indefinite {
+		data = append(data, endOfContents...)
+	}
+	this.setData(data, codec.Profile)
+	return nil
+}
+
+// 是否以不定长方式编码
+func (this *TLVPkg) isIndefinite() bool {
+	return this.Indefinite && this.DataType == DataTypeStruct && this.definite == false
+}
+
+// 子节点中是否有编码为0x00 0x00的节点，即tag为0、数据段为空的通用类型基本数据
+// 这样的节点与不定长方式的结束标记相同，所在的嵌套结构只能使用定长方式
+func hasEndOfContents(node []*TLVObject) bool {
+	for _, child := range node {
+		if isEndOfContents(&child.Pkg) {
+			return true
+		}
+	}
+	return false
+}
+
+// 节点的编码是否与结束标记相同
+func isEndOfContents(pkg *TLVPkg) bool {
+	return pkg.FrameType == ClassUniversal && pkg.DataType == DataTypePrimitive && pkg.TagValue == 0 &&
+		pkg.IsNull() == false && len(pkg.Value) == 0
+}
+
+// 是否为null，即以不定长方式编码的基本数据，数据段始终为空
+func (this *TLVPkg) IsNull() bool {
+	return this.Indefinite && this.DataType != DataTypeStruct
 }
 
 // 获取TLV数据包大小
@@ -70,7 +189,7 @@ func (this *TLVPkg) Size() int {
 获取TLV数据包的字节数据
 */
 func (this *TLVPkg) Bytes() []byte {
-	if this.data == nil {
+	if this.data == nil || this.stale() {
 		this.Build()
 	}
 	return this.data
@@ -103,11 +222,7 @@ func buildTag(frameType byte, dataType byte, tagValue int) (tagBytes []byte) {
 */
 func buildLength(length int) (lenBytes []byte) {
 
-	if length < 0 {
-		fmt.Errorf("长度不能为负数 length = %d\n", length)
-	}
-
-	if length == 0 {
+	if length <= 0 {
 		return []byte{0}
 	}
 
@@ -126,3 +241,235 @@ func buildLength(length int) (lenBytes []byte) {
 	}
 	return lenBytes
 }
+
+// 将TLV数据直接写入io.Writer的编码器
+// 嵌套结构的数据不会先拼接成完整的字节数据，向网络连接写入时建议配合bufio.Writer使用
+type Encoder struct {
+	writer io.Writer
+	depth  int // BeginStruct后尚未结束的嵌套层数
+
+	Codec Codec // 编码规则
+}
+
+// 创建向io.Writer写入TLV数据的编码器
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{writer: w}
+}
+
+// 将TLVObject下的所有节点写入io.Writer，写入结果与BytesWith相同
+// 先计算出每个节点的长度，再依次写入各节点的tag、length及数据
+func (this *Encoder) Encode(tlvObject *TLVObject) error {
+	node := tlvObject.node
+	if this.Codec.Canonical {
+		node = canonicalNode(node)
+	}
+
+	for i := 0; i < len(node); i++ {
+		if _, err := this.measureNode(node[i]); err != nil {
+			return err
+		}
+		if err := this.writeNode(node[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 开始写入一个嵌套结构，数据大小未知，因此使用不定长方式，需要与EndStruct配对使用
+func (this *Encoder) BeginStruct(key int) error {
+	if this.Codec.Canonical {
+		return fmt.Errorf("%w: 规范形式不能使用不定长方式", ErrNonCanonical)
+	}
+
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	frameType, tagValue, _ := splitKey(key)
+	tagBytes, err := this.Codec.buildTag(frameType, DataTypeStruct, tagValue)
+	if err != nil {
+		return err
+	}
+	if err = this.write(tagBytes, this.Codec.indefiniteLength()); err != nil {
+		return err
+	}
+
+	this.depth++
+	return nil
+}
+
+// 写入一个基本数据字段
+// 不定长方式的嵌套结构中不能写入与结束标记相同的字段，即tag为0的通用类型空数据
+func (this *Encoder) WriteField(key int, value []byte) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	frameType, tagValue, _ := splitKey(key)
+	pkg := TLVPkg{
+		FrameType: frameType,
+		DataType:  DataTypePrimitive,
+		TagValue:  tagValue,
+		Value:     value,
+	}
+	if this.depth > 0 && isEndOfContents(&pkg) {
+		return fmt.Errorf("%w: 字段的编码与结束标记相同", ErrInvalidParam)
+	}
+	if _, err := this.measurePkg(&pkg); err != nil {
+		return err
+	}
+	return this.writePkg(&pkg, true)
+}
+
+// 结束最近一次BeginStruct开始的嵌套结构
+func (this *Encoder) EndStruct() error {
+	if this.depth == 0 {
+		return ErrInvalidParam
+	}
+
+	this.depth--
+	return this.write(endOfContents)
+}
+
+// 计算节点编码后的大小，结果保存在TLVPkg的字节数统计中
+func (this *Encoder) measureNode(node *TLVObject) (size int, err error) {
+	if node.rawValid(this.Codec) {
+		return len(node.raw), nil
+	}
+	if node.Pkg.DataType != DataTypeStruct {
+		return this.measurePkg(&node.Pkg)
+	}
+	node.Pkg.definite = hasEndOfContents(node.node)
+	//未修改的子树使用缓存的编码结果
+	if node.cacheValid(this.Codec) {
+		node.Pkg.dataByteCount = len(node.cache)
+		return this.measurePkg(&node.Pkg)
+	}
+
+	valueLen := 0
+	for i := 0; i < len(node.node); i++ {
+		childSize, err := this.measureNode(node.node[i])
+		if err != nil {
+			return 0, err
+		}
+		valueLen += childSize
+	}
+	node.Pkg.dataByteCount = valueLen
+	return this.measurePkg(&node.Pkg)
+}
+
+// 计算tag及length字段的字节数，dataByteCount为数据段长度
+func (this *Encoder) measurePkg(pkg *TLVPkg) (size int, err error) {
+	if pkg.IsNull() {
+		pkg.dataByteCount = 0
+	} else if pkg.DataType != DataTypeStruct {
+		pkg.dataByteCount = len(pkg.Value)
+	}
+
+	tagBytes, lenBytes, err := this.buildHead(pkg, pkg.dataByteCount)
+	if err != nil {
+		return 0, err
+	}
+
+	pkg.tagByteCount = len(tagBytes)
+	pkg.lenByteCount = len(lenBytes)
+	if this.isIndefinite(pkg) {
+		pkg.dataByteCount += len(endOfContents)
+	}
+	return pkg.Size(), nil
+}
+
+// 生成tag及length字段，valueLen为数据段长度
+func (this *Encoder) buildHead(pkg *TLVPkg, valueLen int) (tagBytes []byte, lenBytes []byte, err error) {
+	if pkg.TagValue < 0 {
+		return nil, nil, ErrInvalidParam
+	}
+	if pkg.TagValue > maxClassTagValue {
+		return nil, nil, ErrTagOverflow
+	}
+
+	tagBytes, err = this.Codec.buildTag(pkg.FrameType, pkg.DataType, pkg.TagValue)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if this.isIndefinite(pkg) {
+		lenBytes = this.Codec.indefiniteLength()
+	} else {
+		lenBytes = this.Codec.buildLength(valueLen)
+	}
+	return tagBytes, lenBytes, nil
+}
+
+// 是否以不定长方式写入
+func (this *Encoder) isIndefinite(pkg *TLVPkg) bool {
+	return pkg.IsNull() || (pkg.isIndefinite() && this.Codec.Canonical == false)
+}
+
+// 写入已经计算过大小的节点
+func (this *Encoder) writeNode(node *TLVObject) error {
+	if node.rawValid(this.Codec) {
+		return this.write(node.raw)
+	}
+	if node.Pkg.DataType != DataTypeStruct {
+		return this.writePkg(&node.Pkg, true)
+	}
+
+	if err := this.writePkg(&node.Pkg, false); err != nil {
+		return err
+	}
+
+	if node.cacheValid(this.Codec) {
+		if err := this.write(node.cache); err != nil {
+			return err
+		}
+		if this.isIndefinite(&node.Pkg) {
+			return this.write(endOfContents)
+		}
+		return nil
+	}
+
+	children := node.node
+	if this.Codec.Canonical {
+		children = canonicalNode(children)
+	}
+	for i := 0; i < len(children); i++ {
+		if err := this.writeNode(children[i]); err != nil {
+			return err
+		}
+	}
+
+	if this.isIndefinite(&node.Pkg) {
+		return this.write(endOfContents)
+	}
+	return nil
+}
+
+// 写入tag及length字段，withValue为true时同时写入数据段
+func (this *Encoder) writePkg(pkg *TLVPkg, withValue bool) error {
+	valueLen := pkg.dataByteCount
+	if this.isIndefinite(pkg) {
+		valueLen -= len(endOfContents)
+	}
+
+	tagBytes, lenBytes, err := this.buildHead(pkg, valueLen)
+	if err != nil {
+		return err
+	}
+
+	if pkg.IsNull() {
+		return this.write(tagBytes, lenBytes, endOfContents)
+	}
+	if withValue {
+		return this.write(tagBytes, lenBytes, pkg.Value)
+	}
+	return this.write(tagBytes, lenBytes)
+}
+
+// 依次写入多段数据
+func (this *Encoder) write(chunks ...[]byte) error {
+	for _, chunk := range chunks {
+		if _, err := this.writer.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -15,7 +15,9 @@
 package golang
 
 import (
+	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"math"
 	"sync/atomic"
@@ -316,7 +318,7 @@ func TestBuildLength(t *testing.T) {
 		parseLength := parseLength(lenBytes)
 
 		if rawLength[i] != parseLength {
-			fmt.Errorf("rawLength[%d] = %d, parseLength = %d\n", i, rawLength[i], parseLength)
+			t.Errorf("rawLength[%d] = %d, parseLength = %d\n", i, rawLength[i], parseLength)
 		}
 	}
 
@@ -339,8 +341,8 @@ func TestBuildTag(t *testing.T) {
 				frameType, dataType, tagValue := parseTag(tagBytes)
 
 				if tagValue != rawTagValue[k] || frameType != rawFrameType[i] || dataType != rawDataType[j] {
-					fmt.Errorf("rawdata--> rawTagValue=%d, rawFrameType=%d, rawDataType=%d\n", rawTagValue[k], rawFrameType[i], rawDataType[j])
-					fmt.Errorf("parseResult--> tagValue=%d, frameType=%d, dataType=%d\n", tagValue, frameType, dataType)
+					t.Errorf("rawdata--> rawTagValue=%d, rawFrameType=%d, rawDataType=%d\n", rawTagValue[k], rawFrameType[i], rawDataType[j])
+					t.Errorf("parseResult--> tagValue=%d, frameType=%d, dataType=%d\n", tagValue, frameType, dataType)
 				}
 			}
 		}
@@ -356,3 +358,146 @@ func TestAtomic(t *testing.T) {
 	connId0 := atomic.LoadInt64(&connId)
 	fmt.Println(connId0)
 }
+
+/**
+测试不定长方式的编码和解码
+*/
+func TestIndefiniteLength(t *testing.T) {
+	tlvBuilder := TLVObject{}
+
+	outer := TLVObject{}
+	tlvBuilder.PutIndefinite(1, &outer)
+	outer.PutInt32(0, -300)
+
+	inner := TLVObject{}
+	outer.PutIndefinite(2, &inner)
+	inner.PutString(0, "zhoujunhua")
+	inner.PutBytes(1, []byte{0x00, 0x00, 0x80})
+
+	bytes := tlvBuilder.Bytes()
+	if bytes[1] != 0x80 || bytes[2] != 0x00 {
+		t.Fatalf("长度字段不是不定长方式: %v", bytes)
+	}
+	if bytes[len(bytes)-2] != 0x00 || bytes[len(bytes)-1] != 0x00 {
+		t.Fatalf("缺少结束标记: %v", bytes)
+	}
+
+	check := func(tlvObject *TLVObject) {
+		outerParse, ok := tlvObject.Get(1)
+		if ok == false {
+			t.Fatalf("没有找到outer")
+		}
+		if int32Value, _ := outerParse.GetInt32(0); int32Value != -300 {
+			t.Errorf("int32Value = %v", int32Value)
+		}
+		innerParse, ok := outerParse.Get(2)
+		if ok == false {
+			t.Fatalf("没有找到inner")
+		}
+		if stringValue, _ := innerParse.GetString(0); stringValue != "zhoujunhua" {
+			t.Errorf("stringValue = %v", stringValue)
+		}
+		if bytesValue, _ := innerParse.GetBytes(1); string(bytesValue) != "\x00\x00\x80" {
+			t.Errorf("bytesValue = %v", bytesValue)
+		}
+	}
+
+	tlvParser := TLVObject{}
+	tlvParser.FromBytes(bytes)
+	check(&tlvParser)
+
+	//逐字节将两个连续的数据包输入解码器
+	stream := append(append([]byte{}, bytes...), bytes...)
+	decoder := Decoder{}
+	var tlvArray []TLVObject
+	for i := 0; i < len(stream); i++ {
+		objs, err := decoder.Parse(stream[i:i+1], 1)
+		if err != nil {
+			t.Fatalf("err = %v", err)
+		}
+		tlvArray = append(tlvArray, objs...)
+	}
+	if len(tlvArray) != 2 {
+		t.Fatalf("len(tlvArray) = %d", len(tlvArray))
+	}
+	for i := range tlvArray {
+		check(&tlvArray[i])
+	}
+
+	//添加失败时不修改传入的对象
+	existing := TLVObject{}
+	if err := tlvBuilder.PutIndefinite(ClassTag(ClassUniversal, -1), &existing); err == nil || existing.Pkg.Indefinite {
+		t.Errorf("err = %v, Indefinite = %v", err, existing.Pkg.Indefinite)
+	}
+}
+
+/**
+测试Encoder写入的数据与BytesWith一致
+*/
+func TestEncoderEncode(t *testing.T) {
+	tlvBuilder := TLVObject{}
+	outer := TLVObject{}
+	tlvBuilder.Put(9, &outer)
+	outer.PutString(40, string(make([]byte, 300)))
+	outer.PutInt32(1, -1)
+	inner := TLVObject{}
+	outer.PutIndefinite(2, &inner)
+	inner.PutBool(0, true)
+	tlvBuilder.PutInt8(3, 8)
+
+	codecs := []Codec{{}, {Profile: ProfileBER}, {Profile: ProfileBER, Canonical: true}, {Canonical: true}}
+	for _, codec := range codecs {
+		expected, err := tlvBuilder.BytesWith(codec)
+		if err != nil {
+			t.Fatalf("err = %v", err)
+		}
+
+		var buf bytes.Buffer
+		encoder := NewEncoder(&buf)
+		encoder.Codec = codec
+		if err := encoder.Encode(&tlvBuilder); err != nil {
+			t.Fatalf("err = %v", err)
+		}
+		if !bytes.Equal(buf.Bytes(), expected) {
+			t.Errorf("codec = %v\n%x\n%x", codec, buf.Bytes(), expected)
+		}
+	}
+}
+
+/**
+测试通过BeginStruct/WriteField/EndStruct手动写入的数据
+*/
+func TestEncoderStruct(t *testing.T) {
+	var buf bytes.Buffer
+	encoder := NewEncoder(&buf)
+	encoder.BeginStruct(1)
+	encoder.WriteField(0, []byte("zhoujunhua"))
+	encoder.BeginStruct(ClassTag(ClassApplication, 2))
+	encoder.WriteField(0, []byte{1})
+	encoder.EndStruct()
+	encoder.EndStruct()
+	if err := encoder.EndStruct(); err != ErrInvalidParam {
+		t.Errorf("err = %v", err)
+	}
+
+	tlvObject, err := NewDecoder(&buf).Decode()
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	outer, _ := tlvObject.Get(1)
+	if stringValue, _ := outer.GetString(0); stringValue != "zhoujunhua" {
+		t.Errorf("stringValue = %v", stringValue)
+	}
+	inner, ok := outer.Get(ClassTag(ClassApplication, 2))
+	if ok == false {
+		t.Fatalf("没有找到inner")
+	}
+	if boolValue, _ := inner.GetBool(0); boolValue == false {
+		t.Errorf("boolValue = %v", boolValue)
+	}
+
+	encoder.Codec.Canonical = true
+	if err := encoder.BeginStruct(1); errors.Is(err, ErrNonCanonical) == false {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golang
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrTruncated      = errors.New("TLV数据不完整")
+	ErrLengthOverflow = errors.New("长度字段超出范围")
+	ErrTagOverflow    = errors.New("tag值超出范围")
+	ErrTypeMismatch   = errors.New("字段类型不匹配")
+	ErrNotFound       = errors.New("字段不存在")
+)
+
+// 读取null字段的值时的错误
+var errNull = fmt.Errorf("%w: 字段为null", ErrTypeMismatch)
+
+// TLV编解码及读取字段时的错误，可以通过errors.Is判断具体的错误类型
+type Error struct {
+	Err    error // 具体的错误
+	Offset int   // 出错的TLV包在数据中的字节偏移，与数据位置无关时为-1
+	Path   []int // 出错节点的tag路径
+}
+
+func newError(err error, offset int, path []int) *Error {
+	return &Error{
+		Err:    err,
+		Offset: offset,
+		Path:   append([]int(nil), path...),
+	}
+}
+
+// 读取字段时的错误
+func newKeyError(err error, key int) *Error {
+	_, tagValue, _ := splitKey(key)
+	return newError(err, -1, []int{tagValue})
+}
+
+func (this *Error) Error() string {
+	ret := this.Err.Error()
+	if len(this.Path) > 0 {
+		ret += fmt.Sprintf(", tag路径: %s", formatPath(this.Path))
+	}
+	if this.Offset >= 0 {
+		ret += fmt.Sprintf(", 偏移: %d", this.Offset)
+	}
+	return ret
+}
+
+func (this *Error) Unwrap() error {
+	return this.Err
+}
+
+// 将tag路径格式化为1/4/2的形式
+func formatPath(path []int) string {
+	items := make([]string, len(path))
+	for i, tagValue := range path {
+		items[i] = strconv.Itoa(tagValue)
+	}
+	return strings.Join(items, "/")
+}
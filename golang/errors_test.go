@@ -0,0 +1,90 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golang
+
+import (
+	"errors"
+	"testing"
+)
+
+// 测试截断及溢出的数据返回带有位置信息的错误
+func TestParseError(t *testing.T) {
+	tlvBuilder := TLVObject{}
+	tlvObject := TLVObject{}
+	tlvBuilder.PutInt8(0, 1)
+	tlvBuilder.Put(1, &tlvObject)
+	tlvObject.PutInt32(4, 7)
+	tlvBytes := tlvBuilder.Bytes()
+
+	//去掉最后一个字节后，tag为1的节点不完整
+	tlvParser := TLVObject{}
+	err := tlvParser.FromBytes(tlvBytes[:len(tlvBytes)-1])
+	var tlvErr *Error
+	if errors.As(err, &tlvErr) == false || errors.Is(err, ErrTruncated) == false {
+		t.Fatalf("err = %v", err)
+	}
+	if tlvErr.Offset != 3 || len(tlvErr.Path) != 0 {
+		t.Errorf("offset = %d, path = %v", tlvErr.Offset, tlvErr.Path)
+	}
+
+	//修改子节点长度，使其超出父节点的数据范围
+	broken := append([]byte{}, tlvBytes...)
+	broken[6] = 0x7f
+	tlvParser = TLVObject{}
+	err = tlvParser.FromBytes(broken)
+	if errors.As(err, &tlvErr) == false || errors.Is(err, ErrTruncated) == false || tlvErr.Offset != 5 || formatPath(tlvErr.Path) != "1" {
+		t.Errorf("err = %v", err)
+	}
+
+	tlvParser = TLVObject{}
+	err = tlvParser.FromBytes([]byte{0x01, 0xff, 0xff, 0xff, 0xff, 0x7f})
+	if errors.Is(err, ErrLengthOverflow) == false {
+		t.Errorf("err = %v", err)
+	}
+
+	tlvParser = TLVObject{}
+	err = tlvParser.FromBytesWith([]byte{0x1f, 0xff, 0xff, 0xff, 0xff, 0x7f, 0x00}, Codec{Profile: ProfileBER})
+	if errors.Is(err, ErrTagOverflow) == false {
+		t.Errorf("err = %v", err)
+	}
+
+	//解码器中的错误位置为数据流中的位置
+	decoder := Decoder{}
+	stream := append(append([]byte{}, tlvBytes...), 0x80, 0xff, 0xff, 0xff, 0xff, 0xff)
+	_, err = decoder.Parse(stream, len(stream))
+	if errors.As(err, &tlvErr) == false || errors.Is(err, ErrTagOverflow) == false || tlvErr.Offset != len(tlvBytes) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+// 测试读取字段时返回的错误
+func TestGetError(t *testing.T) {
+	tlvObject := TLVObject{}
+	tlvObject.PutInt16(2, -300)
+	tlvObject.PutBool(3, true)
+
+	if _, err := tlvObject.GetInt32E(2); errors.Is(err, ErrTypeMismatch) == false {
+		t.Errorf("err = %v", err)
+	}
+	if _, err := tlvObject.GetInt32E(5); errors.Is(err, ErrNotFound) == false {
+		t.Errorf("err = %v", err)
+	}
+	if int16Value, err := tlvObject.GetInt16E(2); err != nil || int16Value != -300 {
+		t.Errorf("int16Value = %d, err = %v", int16Value, err)
+	}
+	if boolValue, ok := tlvObject.GetBool(3); ok == false || boolValue == false {
+		t.Errorf("boolValue = %v, ok = %v", boolValue, ok)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// 实现宽TLV嵌套结构的子节点索引
+package golang
+
+// 子节点数达到该值时，查找子节点使用按需建立的索引，避免每次查找都遍历所有子节点
+var indexThreshold = 16
+
+// 获取tag值到子节点位置的索引，子节点较少时返回nil
+// 索引在第一次查找时建立，之后addNode增量更新，其他修改子节点顺序的操作使索引失效
+func (this *TLVObject) lookupIndex() map[int][]int {
+	if len(this.node) < indexThreshold {
+		return nil
+	}
+	if this.index != nil && this.indexOwner == this {
+		return this.index
+	}
+
+	this.index = make(map[int][]int)
+	this.indexOwner = this
+	for i, node := range this.node {
+		this.index[node.Pkg.TagValue] = append(this.index[node.Pkg.TagValue], i)
+	}
+	return this.index
+}
+
+// addNode后更新索引，node已经添加到末尾
+func (this *TLVObject) indexAppend(node *TLVObject) {
+	if this.index == nil {
+		return
+	}
+	//按值复制得到的对象与原对象共用索引，不能修改
+	if this.indexOwner != this {
+		this.index = nil
+		return
+	}
+	this.index[node.Pkg.TagValue] = append(this.index[node.Pkg.TagValue], len(this.node)-1)
+}
+
+// 子节点的位置发生变化，清除索引
+func (this *TLVObject) dropIndex() {
+	this.index = nil
+}
+
+// 按顺序对与key匹配的子节点调用fn，fn返回false时停止
+func (this *TLVObject) eachMatch(key int, fn func(i int, node *TLVObject) bool) {
+	if index := this.lookupIndex(); index != nil {
+		_, tagValue, _ := splitKey(key)
+		for _, i := range index[tagValue] {
+			node := this.node[i]
+			if matchKey(node.Pkg.FrameType, node.Pkg.TagValue, key) && fn(i, node) == false {
+				return
+			}
+		}
+		return
+	}
+
+	for i, node := range this.node {
+		if matchKey(node.Pkg.FrameType, node.Pkg.TagValue, key) && fn(i, node) == false {
+			return
+		}
+	}
+}
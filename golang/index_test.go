@@ -0,0 +1,130 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golang
+
+import (
+	"math"
+	"testing"
+)
+
+// 创建有count个字段的宽结构，tag为0到count-1
+func wideObject(count int) *TLVObject {
+	tlvBuilder := TLVObject{}
+	for i := 0; i < count; i++ {
+		tlvBuilder.PutInt32(i, int32(i))
+	}
+	tlvParser := &TLVObject{}
+	tlvParser.FromBytes(tlvBuilder.Bytes())
+	return tlvParser
+}
+
+func TestIndex(t *testing.T) {
+	tlvObject := wideObject(100)
+	if v, ok := tlvObject.GetInt32(50); ok == false || v != 50 || tlvObject.index == nil {
+		t.Fatalf("v = %v, index = %v", v, tlvObject.index != nil)
+	}
+
+	//添加的节点增量加入索引
+	tlvObject.PutInt32(ClassTag(ClassApplication, 50), -50)
+	if values, _ := tlvObject.GetInt32s(50); len(values) != 2 || values[1] != -50 {
+		t.Errorf("values = %v", values)
+	}
+	if v, _ := tlvObject.GetInt32(ClassTag(ClassApplication, 50)); v != -50 {
+		t.Errorf("v = %v", v)
+	}
+
+	//删除及插入后位置变化，索引重新建立
+	tlvObject.Remove(10)
+	tlvObject.InsertAt(0, tlvObject.ChildAt(tlvObject.Len()-1))
+	if v, _ := tlvObject.GetInt32(11); v != 11 || tlvObject.Has(10) {
+		t.Errorf("v = %v", v)
+	}
+	if tlvObject.findIndex(50, 0) != 0 {
+		t.Errorf("findIndex = %d", tlvObject.findIndex(50, 0))
+	}
+	tlvObject.SetInt32(99, 990)
+	if v, _ := tlvObject.GetPathInt32("99"); v != 990 {
+		t.Errorf("v = %v", v)
+	}
+
+	//直接修改tag后MarkDirty使父节点的索引失效
+	found, _ := tlvObject.Get(30)
+	found.Pkg.TagValue = 3000
+	found.MarkDirty()
+	if v, ok := tlvObject.GetInt32(3000); ok == false || v != 30 || tlvObject.Has(30) {
+		t.Errorf("修改tag后 v = %v, ok = %v", v, ok)
+	}
+	replacement := &TLVObject{}
+	replacement.PutInt32(4000, 40)
+	tlvObject.GetInt32(0)
+	tlvObject.replaceNode(tlvObject.findIndex(40, 0), replacement.node[0])
+	if v, _ := tlvObject.GetInt32(4000); v != 40 || tlvObject.Has(40) {
+		t.Errorf("替换节点后 v = %v", v)
+	}
+
+	//按值复制得到的对象不使用原对象的索引
+	copied := *tlvObject
+	tlvObject.PutInt32(2000, 2)
+	if copied.Has(2000) || tlvObject.Has(2000) == false {
+		t.Errorf("复制的对象索引错误")
+	}
+}
+
+// 读取宽结构的所有字段，比较索引与线性查找
+func BenchmarkGetWide(b *testing.B) {
+	const fieldCount = 500
+	benchmark := func(b *testing.B, threshold int) {
+		saved := indexThreshold
+		indexThreshold = threshold
+		defer func() { indexThreshold = saved }()
+
+		tlvObject := wideObject(fieldCount)
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			for i := 0; i < fieldCount; i++ {
+				if _, ok := tlvObject.GetInt32(i); ok == false {
+					b.Fatal(i)
+				}
+			}
+		}
+	}
+
+	b.Run("index", func(b *testing.B) { benchmark(b, indexThreshold) })
+	b.Run("linear", func(b *testing.B) { benchmark(b, math.MaxInt) })
+}
+
+// 解码后读取所有字段，包含建立索引的开销
+func BenchmarkDecodeAndGetWide(b *testing.B) {
+	const fieldCount = 500
+	data := append([]byte{}, wideObject(fieldCount).Bytes()...)
+	benchmark := func(b *testing.B, threshold int) {
+		saved := indexThreshold
+		indexThreshold = threshold
+		defer func() { indexThreshold = saved }()
+
+		for n := 0; n < b.N; n++ {
+			tlvObject := TLVObject{}
+			if err := tlvObject.FromBytes(data); err != nil {
+				b.Fatal(err)
+			}
+			for i := 0; i < fieldCount; i++ {
+				tlvObject.GetInt32(i)
+			}
+		}
+	}
+
+	b.Run("index", func(b *testing.B) { benchmark(b, indexThreshold) })
+	b.Run("linear", func(b *testing.B) { benchmark(b, math.MaxInt) })
+}
@@ -0,0 +1,273 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// 实现重复字段的读写：多个tag相同的节点，以及将多个定长数值保存在一个节点中的packed数组
+package golang
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// 获取所有匹配key的子节点，按添加顺序返回，没有时返回nil
+func (this *TLVObject) GetAll(key int) []*TLVObject {
+	var ret []*TLVObject
+	this.eachMatch(key, func(i int, node *TLVObject) bool {
+		ret = append(ret, node)
+		return true
+	})
+	return ret
+}
+
+// 按as解码所有匹配key的子节点，没有匹配的节点时返回nil
+func getList[T any](tlvObject *TLVObject, key int, as func(*TLVPkg) (T, error)) ([]T, error) {
+	var ret []T
+	var err error
+	tlvObject.eachMatch(key, func(i int, node *TLVObject) bool {
+		var value T
+		if value, err = as(&node.Pkg); err != nil {
+			return false
+		}
+		ret = append(ret, value)
+		return true
+	})
+	if err != nil {
+		return nil, newKeyError(err, key)
+	}
+	return ret, nil
+}
+
+// 读取多个tag相同的int32节点，没有节点时返回空列表，ok为false表示存在类型不匹配的节点
+func (this *TLVObject) GetInt32s(key int) ([]int32, bool) {
+	ret, err := this.GetInt32sE(key)
+	return ret, err == nil
+}
+
+func (this *TLVObject) GetInt32sE(key int) ([]int32, error) {
+	return getList(this, key, (*TLVPkg).AsInt32)
+}
+
+func (this *TLVObject) GetInt64s(key int) ([]int64, bool) {
+	ret, err := this.GetInt64sE(key)
+	return ret, err == nil
+}
+
+func (this *TLVObject) GetInt64sE(key int) ([]int64, error) {
+	return getList(this, key, (*TLVPkg).AsInt64)
+}
+
+func (this *TLVObject) GetUint32s(key int) ([]uint32, bool) {
+	ret, err := this.GetUint32sE(key)
+	return ret, err == nil
+}
+
+func (this *TLVObject) GetUint32sE(key int) ([]uint32, error) {
+	return getList(this, key, (*TLVPkg).AsUint32)
+}
+
+func (this *TLVObject) GetUint64s(key int) ([]uint64, bool) {
+	ret, err := this.GetUint64sE(key)
+	return ret, err == nil
+}
+
+func (this *TLVObject) GetUint64sE(key int) ([]uint64, error) {
+	return getList(this, key, (*TLVPkg).AsUint64)
+}
+
+func (this *TLVObject) GetStrings(key int) ([]string, bool) {
+	ret, err := this.GetStringsE(key)
+	return ret, err == nil
+}
+
+func (this *TLVObject) GetStringsE(key int) ([]string, error) {
+	return getList(this, key, (*TLVPkg).AsString)
+}
+
+// 为列表中的每个值添加一个tag相同的节点
+func putList[T any](tlvObject *TLVObject, key int, values []T, put func(int, T) error) error {
+	for _, value := range values {
+		if err := put(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 添加多个tag相同的TLV嵌套结构
+func (this *TLVObject) PutList(key int, values []*TLVObject) error {
+	return putList(this, key, values, this.Put)
+}
+
+// 添加多个tag相同的int32节点，可以通过GetInt32s读取
+func (this *TLVObject) PutInt32s(key int, values []int32) error {
+	return putList(this, key, values, this.PutInt32)
+}
+
+func (this *TLVObject) PutInt64s(key int, values []int64) error {
+	return putList(this, key, values, this.PutInt64)
+}
+
+func (this *TLVObject) PutUint32s(key int, values []uint32) error {
+	return putList(this, key, values, this.PutUint32)
+}
+
+func (this *TLVObject) PutUint64s(key int, values []uint64) error {
+	return putList(this, key, values, this.PutUint64)
+}
+
+func (this *TLVObject) PutStrings(key int, values []string) error {
+	return putList(this, key, values, this.PutString)
+}
+
+// 将定长数值依次写入一个节点的数据段，size为每个数值的字节数
+func putPacked[T any](tlvObject *TLVObject, key int, values []T, size int, put func([]byte, T)) error {
+	valueBytes := make([]byte, len(values)*size)
+	for i, value := range values {
+		put(valueBytes[i*size:], value)
+	}
+	return tlvObject.addPrimitiveNode(key, valueBytes)
+}
+
+// 解码putPacked写入的定长数值，数据长度不是size的整数倍时返回ErrTypeMismatch
+func asPacked[T any](pkg *TLVPkg, size int, get func([]byte) T) ([]T, error) {
+	if len(pkg.Value)%size != 0 {
+		return nil, fmt.Errorf("%w: %d字节不是%d字节的整数倍", ErrTypeMismatch, len(pkg.Value), size)
+	}
+
+	ret := make([]T, len(pkg.Value)/size)
+	for i := range ret {
+		ret[i] = get(pkg.Value[i*size:])
+	}
+	return ret, nil
+}
+
+// 读取一个packed节点
+func getPacked[T any](tlvObject *TLVObject, key int, as func(*TLVPkg) ([]T, error)) ([]T, error) {
+	pkg, err := tlvObject.getPkgE(key)
+	if err != nil {
+		return nil, err
+	}
+	ret, err := as(pkg)
+	return ret, keyError(err, key)
+}
+
+// 将多个int32按4字节大端格式连续写入一个节点，比每个值一个节点节省tag及length的开销
+func (this *TLVObject) PutPackedInt32s(key int, values []int32) error {
+	return putPacked(this, key, values, 4, func(b []byte, v int32) { binary.BigEndian.PutUint32(b, uint32(v)) })
+}
+
+func (this *TLVObject) GetPackedInt32s(key int) ([]int32, bool) {
+	ret, err := this.GetPackedInt32sE(key)
+	return ret, err == nil
+}
+
+func (this *TLVObject) GetPackedInt32sE(key int) ([]int32, error) {
+	return getPacked(this, key, (*TLVPkg).AsPackedInt32s)
+}
+
+// 将数据段解码为packed的int32数组
+func (this *TLVPkg) AsPackedInt32s() ([]int32, error) {
+	return asPacked(this, 4, func(b []byte) int32 { return int32(binary.BigEndian.Uint32(b)) })
+}
+
+func (this *TLVObject) PutPackedInt64s(key int, values []int64) error {
+	return putPacked(this, key, values, 8, func(b []byte, v int64) { binary.BigEndian.PutUint64(b, uint64(v)) })
+}
+
+func (this *TLVObject) GetPackedInt64s(key int) ([]int64, bool) {
+	ret, err := this.GetPackedInt64sE(key)
+	return ret, err == nil
+}
+
+func (this *TLVObject) GetPackedInt64sE(key int) ([]int64, error) {
+	return getPacked(this, key, (*TLVPkg).AsPackedInt64s)
+}
+
+// 将数据段解码为packed的int64数组
+func (this *TLVPkg) AsPackedInt64s() ([]int64, error) {
+	return asPacked(this, 8, func(b []byte) int64 { return int64(binary.BigEndian.Uint64(b)) })
+}
+
+func (this *TLVObject) PutPackedUint32s(key int, values []uint32) error {
+	return putPacked(this, key, values, 4, binary.BigEndian.PutUint32)
+}
+
+func (this *TLVObject) GetPackedUint32s(key int) ([]uint32, bool) {
+	ret, err := this.GetPackedUint32sE(key)
+	return ret, err == nil
+}
+
+func (this *TLVObject) GetPackedUint32sE(key int) ([]uint32, error) {
+	return getPacked(this, key, (*TLVPkg).AsPackedUint32s)
+}
+
+// 将数据段解码为packed的uint32数组
+func (this *TLVPkg) AsPackedUint32s() ([]uint32, error) {
+	return asPacked(this, 4, binary.BigEndian.Uint32)
+}
+
+func (this *TLVObject) PutPackedUint64s(key int, values []uint64) error {
+	return putPacked(this, key, values, 8, binary.BigEndian.PutUint64)
+}
+
+func (this *TLVObject) GetPackedUint64s(key int) ([]uint64, bool) {
+	ret, err := this.GetPackedUint64sE(key)
+	return ret, err == nil
+}
+
+func (this *TLVObject) GetPackedUint64sE(key int) ([]uint64, error) {
+	return getPacked(this, key, (*TLVPkg).AsPackedUint64s)
+}
+
+// 将数据段解码为packed的uint64数组
+func (this *TLVPkg) AsPackedUint64s() ([]uint64, error) {
+	return asPacked(this, 8, binary.BigEndian.Uint64)
+}
+
+func (this *TLVObject) PutPackedFloat32s(key int, values []float32) error {
+	return putPacked(this, key, values, 4, func(b []byte, v float32) { binary.BigEndian.PutUint32(b, math.Float32bits(v)) })
+}
+
+func (this *TLVObject) GetPackedFloat32s(key int) ([]float32, bool) {
+	ret, err := this.GetPackedFloat32sE(key)
+	return ret, err == nil
+}
+
+func (this *TLVObject) GetPackedFloat32sE(key int) ([]float32, error) {
+	return getPacked(this, key, (*TLVPkg).AsPackedFloat32s)
+}
+
+// 将数据段解码为packed的float32数组
+func (this *TLVPkg) AsPackedFloat32s() ([]float32, error) {
+	return asPacked(this, 4, func(b []byte) float32 { return math.Float32frombits(binary.BigEndian.Uint32(b)) })
+}
+
+func (this *TLVObject) PutPackedFloat64s(key int, values []float64) error {
+	return putPacked(this, key, values, 8, func(b []byte, v float64) { binary.BigEndian.PutUint64(b, math.Float64bits(v)) })
+}
+
+func (this *TLVObject) GetPackedFloat64s(key int) ([]float64, bool) {
+	ret, err := this.GetPackedFloat64sE(key)
+	return ret, err == nil
+}
+
+func (this *TLVObject) GetPackedFloat64sE(key int) ([]float64, error) {
+	return getPacked(this, key, (*TLVPkg).AsPackedFloat64s)
+}
+
+// 将数据段解码为packed的float64数组
+func (this *TLVPkg) AsPackedFloat64s() ([]float64, error) {
+	return asPacked(this, 8, func(b []byte) float64 { return math.Float64frombits(binary.BigEndian.Uint64(b)) })
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golang
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestRepeated(t *testing.T) {
+	items := []*TLVObject{{}, {}}
+	items[0].PutString(0, "apple")
+	items[1].PutString(0, "pear")
+
+	tlvBuilder := TLVObject{}
+	tlvBuilder.PutInt32s(1, []int32{1, -2, 3})
+	tlvBuilder.PutStrings(2, []string{"a", "", "c"})
+	tlvBuilder.PutList(3, items)
+	tlvBuilder.PutInt32(ClassTag(ClassApplication, 1), 4)
+
+	tlvParser := TLVObject{}
+	if err := tlvParser.FromBytes(tlvBuilder.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+
+	//不带帧类型的key匹配任意帧类型
+	if values, ok := tlvParser.GetInt32s(1); ok == false || reflect.DeepEqual(values, []int32{1, -2, 3, 4}) == false {
+		t.Errorf("values = %v", values)
+	}
+	if values, ok := tlvParser.GetInt32s(ClassTag(ClassUniversal, 1)); ok == false || len(values) != 3 {
+		t.Errorf("values = %v", values)
+	}
+	if values, ok := tlvParser.GetStrings(2); ok == false || reflect.DeepEqual(values, []string{"a", "", "c"}) == false {
+		t.Errorf("values = %v", values)
+	}
+
+	all := tlvParser.GetAll(3)
+	if len(all) != 2 {
+		t.Fatalf("all = %v", all)
+	}
+	if name, _ := all[1].GetString(0); name != "pear" {
+		t.Errorf("name = %q", name)
+	}
+
+	if values, err := tlvParser.GetInt64sE(9); err != nil || values != nil {
+		t.Errorf("values = %v, err = %v", values, err)
+	}
+	if _, err := tlvParser.GetInt64sE(1); errors.Is(err, ErrTypeMismatch) == false {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestPacked(t *testing.T) {
+	tlvBuilder := TLVObject{}
+	tlvBuilder.PutPackedInt32s(1, []int32{1, -1})
+	tlvBuilder.PutPackedUint64s(2, []uint64{math.MaxUint64})
+	tlvBuilder.PutPackedFloat64s(3, []float64{0.5, -2})
+	tlvBuilder.PutPackedInt64s(4, nil)
+	tlvBuilder.PutBytes(5, []byte{1, 2, 3})
+
+	//所有数值保存在一个节点中
+	want := []byte{0x01, 0x08, 0, 0, 0, 1, 0xff, 0xff, 0xff, 0xff}
+	if bytes.Equal(tlvBuilder.Bytes()[:10], want) == false {
+		t.Errorf("%x", tlvBuilder.Bytes())
+	}
+
+	tlvParser := TLVObject{}
+	if err := tlvParser.FromBytes(tlvBuilder.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if values, ok := tlvParser.GetPackedInt32s(1); ok == false || reflect.DeepEqual(values, []int32{1, -1}) == false {
+		t.Errorf("values = %v", values)
+	}
+	if values, ok := tlvParser.GetPackedUint64s(2); ok == false || reflect.DeepEqual(values, []uint64{math.MaxUint64}) == false {
+		t.Errorf("values = %v", values)
+	}
+	if values, ok := tlvParser.GetPackedFloat64s(3); ok == false || reflect.DeepEqual(values, []float64{0.5, -2}) == false {
+		t.Errorf("values = %v", values)
+	}
+	if values, ok := tlvParser.GetPackedInt64s(4); ok == false || len(values) != 0 {
+		t.Errorf("values = %v", values)
+	}
+	if _, err := tlvParser.GetPackedInt32sE(5); errors.Is(err, ErrTypeMismatch) == false {
+		t.Errorf("err = %v", err)
+	}
+}
+
+type testSeries struct {
+	Points  []int32   `tlv:"1,packed"`
+	Weights []float32 `tlv:"2,packed"`
+	Labels  []string  `tlv:"3"`
+}
+
+func TestMarshalPacked(t *testing.T) {
+	src := testSeries{Points: []int32{7, -7}, Weights: []float32{1.5}, Labels: []string{"x", "y"}}
+	data, err := Marshal(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tlvBuilder := TLVObject{}
+	tlvBuilder.PutPackedInt32s(1, src.Points)
+	tlvBuilder.PutPackedFloat32s(2, src.Weights)
+	tlvBuilder.PutStrings(3, src.Labels)
+	if bytes.Equal(tlvBuilder.Bytes(), data) == false {
+		t.Errorf("\n%x\n%x", tlvBuilder.Bytes(), data)
+	}
+
+	var dst testSeries
+	if err = Unmarshal(data, &dst); err != nil || reflect.DeepEqual(dst, src) == false {
+		t.Errorf("dst = %+v, err = %v", dst, err)
+	}
+
+	var invalid struct {
+		Names []string `tlv:"1,packed"`
+	}
+	invalid.Names = []string{"a"}
+	if _, err = Marshal(invalid); errors.Is(err, ErrUnsupportedType) == false {
+		t.Errorf("err = %v", err)
+	}
+}
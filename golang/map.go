@@ -0,0 +1,152 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// 实现map的编码约定：每个键值对编码为一个tag相同的TLV嵌套结构，其中键的tag为0，值的tag为1
+package golang
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// 键值对嵌套结构中键及值的tag
+const (
+	MapKeyTag   = 0
+	MapValueTag = 1
+)
+
+// 将键值对编码为嵌套结构
+func newMapEntry[K comparable, V any](k K, v V, putKey func(*TLVObject, int, K) error, putValue func(*TLVObject, int, V) error) (*TLVObject, error) {
+	entry := &TLVObject{}
+	if err := putKey(entry, MapKeyTag, k); err != nil {
+		return nil, err
+	}
+	if err := putValue(entry, MapValueTag, v); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// 添加一个map，每个键值对添加一个tag为key的嵌套结构，顺序与map的遍历顺序相同
+// putKey及putValue为写入键及值的方法，如(*TLVObject).PutString
+func PutMap[K comparable, V any](tlvObject *TLVObject, key int, m map[K]V, putKey func(*TLVObject, int, K) error, putValue func(*TLVObject, int, V) error) error {
+	for k, v := range m {
+		entry, err := newMapEntry(k, v, putKey, putValue)
+		if err != nil {
+			return err
+		}
+		if err = tlvObject.Put(key, entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 与PutMap相同，但键值对按键编码后的数据段排序，同一个map总是得到相同的编码结果，可用于规范形式
+func PutSortedMap[K comparable, V any](tlvObject *TLVObject, key int, m map[K]V, putKey func(*TLVObject, int, K) error, putValue func(*TLVObject, int, V) error) error {
+	entries := make([]*TLVObject, 0, len(m))
+	for k, v := range m {
+		entry, err := newMapEntry(k, v, putKey, putValue)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+	}
+
+	sortMapEntries(entries)
+	return tlvObject.PutList(key, entries)
+}
+
+// 按键编码后的数据段逐字节比较，对键值对排序，字符串即按字典序
+// 只有定长编码的无符号整数按数值排序；有符号整数及varint等变长编码的整数不按数值排序，
+// 如varint编码的300(01 2c)排在7(07)之前
+func sortMapEntries(entries []*TLVObject) {
+	keys := make(map[*TLVObject][]byte, len(entries))
+	for _, entry := range entries {
+		if keyNode, ok := entry.Get(MapKeyTag); ok {
+			keys[entry] = keyNode.Pkg.Value
+			if keyNode.Pkg.DataType == DataTypeStruct {
+				keys[entry], _ = buildNode(keyNode.node, Codec{})
+			}
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return bytes.Compare(keys[entries[i]], keys[entries[j]]) < 0
+	})
+}
+
+// 读取PutMap写入的map，getKey及getValue为读取键及值的方法，如(*TLVObject).GetStringE
+// 没有值的键值对使用值类型的零值，没有键值对时返回空map
+func GetMap[K comparable, V any](tlvObject *TLVObject, key int, getKey func(*TLVObject, int) (K, error), getValue func(*TLVObject, int) (V, error)) (map[K]V, error) {
+	ret := make(map[K]V)
+	for _, entry := range tlvObject.GetAll(key) {
+		if entry.Pkg.DataType != DataTypeStruct {
+			return nil, newKeyError(fmt.Errorf("%w: 键值对需要TLV嵌套结构", ErrTypeMismatch), key)
+		}
+
+		k, err := getKey(entry, MapKeyTag)
+		if err != nil {
+			return nil, prependPath(err, key)
+		}
+
+		var v V
+		if _, ok := entry.Get(MapValueTag); ok {
+			if v, err = getValue(entry, MapValueTag); err != nil {
+				return nil, prependPath(err, key)
+			}
+		}
+		ret[k] = v
+	}
+	return ret, nil
+}
+
+// 在错误的tag路径前加上父节点的tag
+func prependPath(err error, key int) error {
+	_, tagValue, _ := splitKey(key)
+	var tlvErr *Error
+	if errors.As(err, &tlvErr) {
+		return newError(tlvErr.Err, tlvErr.Offset, append([]int{tagValue}, tlvErr.Path...))
+	}
+	return newKeyError(err, key)
+}
+
+// 写入键为字符串、值为int64的map，键值对按键排序
+func (this *TLVObject) PutStringInt64Map(key int, m map[string]int64) error {
+	return PutSortedMap(this, key, m, (*TLVObject).PutString, (*TLVObject).PutInt64)
+}
+
+func (this *TLVObject) GetStringInt64Map(key int) (map[string]int64, bool) {
+	ret, err := this.GetStringInt64MapE(key)
+	return ret, err == nil
+}
+
+func (this *TLVObject) GetStringInt64MapE(key int) (map[string]int64, error) {
+	return GetMap(this, key, (*TLVObject).GetStringE, (*TLVObject).GetInt64E)
+}
+
+// 写入键为uint32、值为TLV嵌套结构的map，键值对按键排序，值为nil时只写入键
+func (this *TLVObject) PutUint32ObjectMap(key int, m map[uint32]*TLVObject) error {
+	return PutSortedMap(this, key, m, (*TLVObject).PutUint32, (*TLVObject).putObject)
+}
+
+func (this *TLVObject) GetUint32ObjectMap(key int) (map[uint32]*TLVObject, bool) {
+	ret, err := this.GetUint32ObjectMapE(key)
+	return ret, err == nil
+}
+
+func (this *TLVObject) GetUint32ObjectMapE(key int) (map[uint32]*TLVObject, error) {
+	return GetMap(this, key, (*TLVObject).GetUint32E, (*TLVObject).GetE)
+}
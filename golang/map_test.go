@@ -0,0 +1,125 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golang
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMap(t *testing.T) {
+	scores := map[string]int64{"bob": -2, "": 0, "alice": 1 << 40}
+	child := &TLVObject{}
+	child.PutString(0, "route")
+	routes := map[uint32]*TLVObject{80: child, 443: nil}
+
+	tlvBuilder := TLVObject{}
+	if err := tlvBuilder.PutStringInt64Map(1, scores); err != nil {
+		t.Fatal(err)
+	}
+	if err := tlvBuilder.PutUint32ObjectMap(2, routes); err != nil {
+		t.Fatal(err)
+	}
+
+	//每个键值对为一个嵌套结构，按键排序
+	entries := tlvBuilder.GetAll(1)
+	if len(entries) != 3 {
+		t.Fatalf("entries = %v", entries)
+	}
+	if k, _ := entries[1].GetString(MapKeyTag); k != "alice" {
+		t.Errorf("k = %q", k)
+	}
+
+	tlvParser := TLVObject{}
+	if err := tlvParser.FromBytes(tlvBuilder.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if m, ok := tlvParser.GetStringInt64Map(1); ok == false || reflect.DeepEqual(m, scores) == false {
+		t.Errorf("m = %v", m)
+	}
+	m, err := tlvParser.GetUint32ObjectMapE(2)
+	if err != nil || len(m) != 2 || m[443] != nil {
+		t.Fatalf("m = %v, err = %v", m, err)
+	}
+	if name, _ := m[80].GetString(0); name != "route" {
+		t.Errorf("name = %q", name)
+	}
+
+	if _, err = tlvParser.GetStringInt64MapE(2); errors.Is(err, ErrTypeMismatch) == false || formatPath(err.(*Error).Path) != "2/1" {
+		t.Errorf("err = %v", err)
+	}
+
+	//无效的key返回错误，不添加任何节点
+	invalid := TLVObject{}
+	if err = PutMap(&invalid, maxClassTagValue+1, scores, (*TLVObject).PutString, (*TLVObject).PutInt64); errors.Is(err, ErrTagOverflow) == false || invalid.Len() != 0 {
+		t.Errorf("err = %v, len = %d", err, invalid.Len())
+	}
+}
+
+// 测试排序后的编码结果与map的遍历顺序无关
+func TestSortedMap(t *testing.T) {
+	m := map[int32]string{}
+	for i := int32(-50); i < 50; i++ {
+		m[i] = "v"
+	}
+
+	var first []byte
+	for i := 0; i < 5; i++ {
+		tlvObject := TLVObject{}
+		PutSortedMap(&tlvObject, 3, m, (*TLVObject).PutInt32, (*TLVObject).PutString)
+		data, _ := tlvObject.BytesWith(Codec{Canonical: true})
+		if first == nil {
+			first = data
+		} else if bytes.Equal(first, data) == false {
+			t.Fatalf("编码结果不确定")
+		}
+	}
+
+	tlvObject := TLVObject{}
+	tlvObject.FromBytes(first)
+	decoded, err := GetMap(&tlvObject, 3, (*TLVObject).GetInt32E, (*TLVObject).GetStringE)
+	if err != nil || reflect.DeepEqual(decoded, m) == false {
+		t.Errorf("decoded = %v, err = %v", decoded, err)
+	}
+}
+
+type testInventory struct {
+	Stock  map[string]int64       `tlv:"1,sorted"`
+	Places map[uint32]testAddress `tlv:"2,sorted,varint"`
+}
+
+func TestMarshalMap(t *testing.T) {
+	src := testInventory{
+		Stock:  map[string]int64{"pen": 3, "ink": -1},
+		Places: map[uint32]testAddress{7: {City: "x"}, 300: {City: "y"}},
+	}
+	data, err := Marshal(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tlvBuilder := TLVObject{}
+	tlvBuilder.PutStringInt64Map(1, src.Stock)
+	if bytes.Equal(tlvBuilder.Bytes(), data[:len(tlvBuilder.Bytes())]) == false {
+		t.Errorf("\n%x\n%x", tlvBuilder.Bytes(), data)
+	}
+
+	var dst testInventory
+	if err = Unmarshal(data, &dst); err != nil || reflect.DeepEqual(dst, src) == false {
+		t.Errorf("dst = %+v, err = %v", dst, err)
+	}
+}
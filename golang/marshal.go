@@ -0,0 +1,843 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// 通过反射实现结构体与TLV数据的转换
+package golang
+
+import (
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnsupportedType = errors.New("不支持的数据类型")
+)
+
+// 自定义类型的TLV编码接口
+// tlvObject为该值对应的TLV嵌套结构节点，实现者向其中添加子节点
+type TLVMarshaler interface {
+	MarshalTLV(tlvObject *TLVObject) error
+}
+
+// 自定义类型的TLV解码接口
+// tlvObject为该值对应的TLV嵌套结构节点，实现者从中读取子节点
+type TLVUnmarshaler interface {
+	UnmarshalTLV(tlvObject *TLVObject) error
+}
+
+var (
+	tlvMarshalerType    = reflect.TypeOf((*TLVMarshaler)(nil)).Elem()
+	binaryMarshalerType = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	unknownFieldsType   = reflect.TypeOf(UnknownFields(nil))
+	timeType            = reflect.TypeOf(time.Time{})
+)
+
+// 添加一个自定义类型的节点
+// v依次尝试TLVMarshaler、encoding.BinaryMarshaler及encoding.TextMarshaler，
+// TLVMarshaler编码为嵌套结构，其余两种编码为基本数据
+func (this *TLVObject) PutMarshaler(key int, v interface{}) error {
+	ok, err := this.putMarshaler(key, v)
+	if err == nil && ok == false {
+		err = fmt.Errorf("%w: %T没有实现编码接口", ErrUnsupportedType, v)
+	}
+	return err
+}
+
+func (this *TLVObject) putMarshaler(key int, v interface{}) (ok bool, err error) {
+	switch m := v.(type) {
+	case TLVMarshaler:
+		child := &TLVObject{}
+		if err = m.MarshalTLV(child); err != nil {
+			return true, err
+		}
+		return true, this.Put(key, child)
+	case encoding.BinaryMarshaler:
+		valueBytes, err := m.MarshalBinary()
+		if err != nil {
+			return true, err
+		}
+		return true, this.addPrimitiveNode(key, valueBytes)
+	case encoding.TextMarshaler:
+		valueBytes, err := m.MarshalText()
+		if err != nil {
+			return true, err
+		}
+		return true, this.addPrimitiveNode(key, valueBytes)
+	}
+	return false, nil
+}
+
+// 读取一个自定义类型的节点，v为指针
+// v依次尝试TLVUnmarshaler、encoding.BinaryUnmarshaler及encoding.TextUnmarshaler
+func (this *TLVObject) GetUnmarshaler(key int, v interface{}) error {
+	findObject, err := this.GetE(key)
+	if err != nil {
+		return err
+	}
+
+	ok, err := unmarshalNode(findObject, v)
+	if err == nil && ok == false {
+		err = fmt.Errorf("%w: %T没有实现解码接口", ErrUnsupportedType, v)
+	}
+	if err != nil {
+		return newKeyError(err, key)
+	}
+	return nil
+}
+
+// 按自定义解码接口解码节点，ok表示v实现了其中一个接口
+func unmarshalNode(node *TLVObject, v interface{}) (ok bool, err error) {
+	switch u := v.(type) {
+	case TLVUnmarshaler:
+		if node.Pkg.DataType != DataTypeStruct {
+			return true, fmt.Errorf("%w: %T需要TLV嵌套结构", ErrTypeMismatch, v)
+		}
+		return true, u.UnmarshalTLV(node)
+	case encoding.BinaryUnmarshaler:
+		return true, u.UnmarshalBinary(node.Pkg.Value)
+	case encoding.TextUnmarshaler:
+		return true, u.UnmarshalText(node.Pkg.Value)
+	}
+	return false, nil
+}
+
+// 是否实现了自定义编码接口
+func isMarshaler(t reflect.Type) bool {
+	return t.Implements(tlvMarshalerType) || t.Implements(binaryMarshalerType) || t.Implements(textMarshalerType)
+}
+
+// 结构体字段的编码信息，来自形如`tlv:"3,omitempty,varint"`的标签
+type fieldInfo struct {
+	name      string
+	index     int
+	key       int // 由帧类型及tag值生成的key
+	tagValue  int
+	omitEmpty bool
+	varint    bool
+	compact   bool
+	packed    bool
+	sorted    bool
+}
+
+// 结构体的编码信息
+type structInfo struct {
+	fields  []fieldInfo
+	byKey   map[int]int // key到fields下标的映射
+	unknown int         // UnknownFields类型字段的下标，没有时为-1
+}
+
+var structInfoCache sync.Map // reflect.Type -> *structInfo
+
+// 获取结构体的编码信息，结果会被缓存
+func getStructInfo(t reflect.Type) (*structInfo, error) {
+	if cached, ok := structInfoCache.Load(t); ok {
+		return cached.(*structInfo), nil
+	}
+
+	info := &structInfo{byKey: make(map[int]int), unknown: -1}
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+		if structField.Type == unknownFieldsType && structField.PkgPath == "" {
+			info.unknown = i
+			continue
+		}
+		tag, ok := structField.Tag.Lookup("tlv")
+		if ok == false || tag == "-" || structField.PkgPath != "" {
+			continue
+		}
+
+		field, err := parseFieldTag(tag)
+		if err != nil {
+			return nil, fmt.Errorf("%w: 字段%s.%s", err, t.Name(), structField.Name)
+		}
+		field.name = structField.Name
+		field.index = i
+
+		if _, ok := info.byKey[field.key]; ok {
+			return nil, fmt.Errorf("%w: 字段%s.%s的tag重复", ErrInvalidParam, t.Name(), structField.Name)
+		}
+		info.byKey[field.key] = len(info.fields)
+		info.fields = append(info.fields, field)
+	}
+
+	cached, _ := structInfoCache.LoadOrStore(t, info)
+	return cached.(*structInfo), nil
+}
+
+// 解析字段标签
+func parseFieldTag(tag string) (field fieldInfo, err error) {
+	items := strings.Split(tag, ",")
+	tagValue, err := strconv.Atoi(items[0])
+	if err != nil || tagValue < 0 || tagValue > maxClassTagValue {
+		return field, fmt.Errorf("%w: tlv标签%q无效", ErrInvalidParam, tag)
+	}
+
+	class := ClassUniversal
+	for _, option := range items[1:] {
+		switch option {
+		case "omitempty":
+			field.omitEmpty = true
+		case "varint":
+			field.varint = true
+		case "compact":
+			field.compact = true
+		case "packed":
+			field.packed = true
+		case "sorted":
+			field.sorted = true
+		case "universal":
+			class = ClassUniversal
+		case "application":
+			class = ClassApplication
+		case "context":
+			class = ClassContext
+		case "private":
+			class = ClassPrivate
+		default:
+			return field, fmt.Errorf("%w: tlv标签%q的选项%q无效", ErrInvalidParam, tag, option)
+		}
+	}
+
+	if field.varint && field.compact {
+		return field, fmt.Errorf("%w: tlv标签%q不能同时使用varint及compact", ErrInvalidParam, tag)
+	}
+
+	field.tagValue = tagValue
+	field.key = ClassTag(class, tagValue)
+	return field, nil
+}
+
+// 将结构体编码为TLV字节数据，结构体的每个字段对应一个顶层TLV包
+//
+// 只编码带有`tlv:"N"`标签的字段，N为tag值，标签中可以追加以下选项：
+//
+//	omitempty   零值时不编码
+//	varint      整数按数值大小使用1/2/4/8字节编码，默认按字段类型的位数编码，int及uint为8字节
+//	compact     整数按PutCompactInt及PutCompactUint的方式编码
+//	packed      整数及浮点数切片按定长大端格式连续编码在一个节点中，与PutPackedInt32s等相同
+//	sorted      map的键值对按键编码后的数据段排序，与PutSortedMap相同，否则顺序不确定
+//	universal/application/context/private  帧类型，默认为universal
+//
+// float32/float64按IEEE-754编码，time.Time及time.Duration按PutTime及PutDuration的方式编码，
+// big.Int按PutBigInt的方式编码，Decimal按PutDecimal的方式编码，
+// 嵌套的结构体编码为TLV嵌套结构，切片编码为多个tag相同的节点，[]byte编码为一个节点，
+// map按PutMap的约定编码为多个tag相同的键值对嵌套结构，varint等选项同时作用于键及值，
+// 空指针不编码，实现了TLVMarshaler等编码接口的类型按PutMarshaler的方式编码，
+// UnknownFields类型的字段保存的节点追加在最后
+func Marshal(v interface{}) ([]byte, error) {
+	tlvObject, err := MarshalObject(v)
+	if err != nil {
+		return nil, err
+	}
+	return tlvObject.BytesWith(Codec{})
+}
+
+// 将结构体转换为TLVObject，可以再通过BytesWith或Encoder按指定的编码规则输出
+func MarshalObject(v interface{}) (*TLVObject, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && rv.IsNil() == false {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: 需要结构体, 实际为%v", ErrUnsupportedType, rv.Kind())
+	}
+
+	//复制为可寻址的值，使得指针接收者实现的编码接口也能生效
+	if rv.CanAddr() == false {
+		addressable := reflect.New(rv.Type()).Elem()
+		addressable.Set(rv)
+		rv = addressable
+	}
+
+	tlvObject := &TLVObject{}
+	if err := encodeStruct(tlvObject, rv, nil); err != nil {
+		return nil, err
+	}
+	return tlvObject, nil
+}
+
+// 将结构体的字段添加到tlvObject下
+func encodeStruct(tlvObject *TLVObject, rv reflect.Value, path []int) error {
+	info, err := getStructInfo(rv.Type())
+	if err != nil {
+		return newError(err, -1, path)
+	}
+
+	for i := range info.fields {
+		field := &info.fields[i]
+		fv := rv.Field(field.index)
+		if field.omitEmpty && fv.IsZero() {
+			continue
+		}
+		if err := encodeValue(tlvObject, fv, field, append(path, field.tagValue)); err != nil {
+			return err
+		}
+	}
+
+	if info.unknown >= 0 {
+		rv.Field(info.unknown).Interface().(UnknownFields).PutTo(tlvObject)
+	}
+	return nil
+}
+
+// 是否为*time.Time或*big.Int
+func isTimeOrBigIntPtr(t reflect.Type) bool {
+	return t.Kind() == reflect.Ptr && (t.Elem() == timeType || t.Elem() == bigIntType)
+}
+
+// 将一个字段的值添加到tlvObject下，切片会编码为多个tag相同的节点
+func encodeValue(tlvObject *TLVObject, fv reflect.Value, field *fieldInfo, path []int) error {
+	key := field.key
+
+	//time.Time及big.Int实现了encoding.BinaryMarshaler等接口，但使用PutTime及PutBigInt的编码
+	if isTimeOrBigIntPtr(fv.Type()) {
+		if fv.IsNil() {
+			return nil
+		}
+		fv = fv.Elem()
+	}
+	switch fv.Type() {
+	case timeType:
+		if err := tlvObject.PutTime(key, fv.Interface().(time.Time)); err != nil {
+			return newError(err, -1, path)
+		}
+		return nil
+	case bigIntType:
+		value := fv.Interface().(big.Int)
+		return tlvObject.PutBigInt(key, &value)
+	}
+
+	if fv.Kind() != reflect.Ptr || fv.IsNil() == false {
+		if fv.CanAddr() && fv.Kind() != reflect.Ptr && isMarshaler(fv.Addr().Type()) {
+			fv = fv.Addr()
+		}
+		if isMarshaler(fv.Type()) {
+			if _, err := tlvObject.putMarshaler(key, fv.Interface()); err != nil {
+				return newError(err, -1, path)
+			}
+			return nil
+		}
+	}
+
+	switch fv.Kind() {
+	case reflect.Ptr:
+		if fv.IsNil() {
+			return nil
+		}
+		return encodeValue(tlvObject, fv.Elem(), field, path)
+	case reflect.Bool:
+		return tlvObject.PutBool(key, fv.Bool())
+	case reflect.Int8:
+		return tlvObject.putInt(key, fv.Int(), 1, field)
+	case reflect.Int16:
+		return tlvObject.putInt(key, fv.Int(), 2, field)
+	case reflect.Int32:
+		return tlvObject.putInt(key, fv.Int(), 4, field)
+	case reflect.Int, reflect.Int64:
+		return tlvObject.putInt(key, fv.Int(), 8, field)
+	case reflect.Uint8:
+		return tlvObject.putUint(key, fv.Uint(), 1, field)
+	case reflect.Uint16:
+		return tlvObject.putUint(key, fv.Uint(), 2, field)
+	case reflect.Uint32:
+		return tlvObject.putUint(key, fv.Uint(), 4, field)
+	case reflect.Uint, reflect.Uint64:
+		return tlvObject.putUint(key, fv.Uint(), 8, field)
+	case reflect.Float32:
+		return tlvObject.PutFloat32(key, float32(fv.Float()))
+	case reflect.Float64:
+		return tlvObject.PutFloat64(key, fv.Float())
+	case reflect.String:
+		return tlvObject.addPrimitiveNode(key, []byte(fv.String()))
+	case reflect.Struct:
+		child := &TLVObject{}
+		if err := encodeStruct(child, fv, path); err != nil {
+			return err
+		}
+		return tlvObject.Put(key, child)
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() == reflect.Uint8 {
+			return tlvObject.addPrimitiveNode(key, fv.Bytes())
+		}
+		if field.packed {
+			return encodePacked(tlvObject, fv, field, path)
+		}
+		if fv.Type().Elem().Kind() == reflect.Slice && fv.Type().Elem().Elem().Kind() != reflect.Uint8 {
+			break
+		}
+		for i := 0; i < fv.Len(); i++ {
+			if err := encodeValue(tlvObject, fv.Index(i), field, path); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		return encodeMap(tlvObject, fv, field, path)
+	}
+
+	err := fmt.Errorf("%w: 字段%s的类型为%v", ErrUnsupportedType, field.name, fv.Type())
+	return newError(err, -1, path)
+}
+
+// 按字段的编码选项添加有符号整数，digit为字段类型的字节数
+func (this *TLVObject) putInt(key int, value int64, digit int, field *fieldInfo) error {
+	if field.compact {
+		return this.PutCompactInt(key, value)
+	}
+	if field.varint {
+		return this.PutVarInt(key, value)
+	}
+	return this.putFixed(key, uint64(value), digit)
+}
+
+// 按字段的编码选项添加无符号整数，digit为字段类型的字节数
+func (this *TLVObject) putUint(key int, value uint64, digit int, field *fieldInfo) error {
+	if field.compact {
+		return this.PutCompactUint(key, value)
+	}
+	if field.varint {
+		return this.PutVarUint(key, value)
+	}
+	return this.putFixed(key, value, digit)
+}
+
+// 按指定位数添加整数
+func (this *TLVObject) putFixed(key int, value uint64, digit int) error {
+	switch digit {
+	case 1:
+		return this.PutUint8(key, uint8(value))
+	case 2:
+		return this.PutUint16(key, uint16(value))
+	case 4:
+		return this.PutUint32(key, uint32(value))
+	}
+	return this.PutUint64(key, value)
+}
+
+// 将TLV字节数据解码到结构体指针v中
+func Unmarshal(data []byte, v interface{}) error {
+	tlvObject := &TLVObject{}
+	if err := tlvObject.FromBytes(data); err != nil {
+		return err
+	}
+	return UnmarshalObject(tlvObject, v)
+}
+
+// 将TLVObject下的节点解码到结构体指针v中
+// 没有对应字段的节点保存在UnknownFields类型的字段中，结构体没有该字段时忽略
+// null节点解码为字段类型的零值，即指针、切片及map为nil
+func UnmarshalObject(tlvObject *TLVObject, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("%w: 需要非空的结构体指针", ErrInvalidParam)
+	}
+
+	rv = rv.Elem()
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("%w: 需要结构体, 实际为%v", ErrUnsupportedType, rv.Kind())
+	}
+	return decodeStruct(tlvObject, rv, nil)
+}
+
+// 将tlvObject下的节点解码到结构体的各个字段
+// 非切片字段使用第一个匹配的节点，切片字段依次追加所有匹配的节点
+func decodeStruct(tlvObject *TLVObject, rv reflect.Value, path []int) error {
+	info, err := getStructInfo(rv.Type())
+	if err != nil {
+		return newError(err, -1, path)
+	}
+
+	var unknown UnknownFields
+	seen := make([]bool, len(info.fields))
+	for _, child := range tlvObject.node {
+		i, ok := info.byKey[ClassTag(child.Pkg.FrameType, child.Pkg.TagValue)]
+		if ok == false {
+			if info.unknown >= 0 {
+				unknown.Add(child)
+			}
+			continue
+		}
+
+		field := &info.fields[i]
+		fv := rv.Field(field.index)
+		childPath := append(path, field.tagValue)
+
+		if fv.Kind() == reflect.Map {
+			//null表示map为nil
+			if child.Pkg.IsNull() {
+				if seen[i] == false {
+					fv.Set(reflect.Zero(fv.Type()))
+				}
+				continue
+			}
+			if seen[i] == false {
+				fv.Set(reflect.MakeMap(fv.Type()))
+				seen[i] = true
+			}
+			if err := decodeMapEntry(child, fv, field, childPath); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if isRepeated(fv.Type()) && field.packed == false {
+			if seen[i] == false {
+				fv.Set(reflect.Zero(fv.Type()))
+				seen[i] = true
+			}
+			elem := reflect.New(fv.Type().Elem()).Elem()
+			if err := decodeValue(child, elem, field, childPath); err != nil {
+				return err
+			}
+			fv.Set(reflect.Append(fv, elem))
+			continue
+		}
+
+		if seen[i] {
+			continue
+		}
+		seen[i] = true
+		if err := decodeValue(child, fv, field, childPath); err != nil {
+			return err
+		}
+	}
+
+	if info.unknown >= 0 {
+		rv.Field(info.unknown).Set(reflect.ValueOf(unknown))
+	}
+	return nil
+}
+
+// 是否为编码成多个节点的切片类型
+func isRepeated(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8
+}
+
+// 将一个节点的数据解码到fv中
+func decodeValue(node *TLVObject, fv reflect.Value, field *fieldInfo, path []int) error {
+	value := node.Pkg.Value
+	if node.Pkg.IsNull() {
+		fv.Set(reflect.Zero(fv.Type()))
+		return nil
+	}
+
+	if isTimeOrBigIntPtr(fv.Type()) {
+		if fv.IsNil() {
+			fv.Set(reflect.New(fv.Type().Elem()))
+		}
+		fv = fv.Elem()
+	}
+	switch fv.Type() {
+	case timeType:
+		ret, err := node.Pkg.AsTime()
+		if err != nil {
+			return newError(err, -1, path)
+		}
+		fv.Set(reflect.ValueOf(ret))
+		return nil
+	case bigIntType:
+		ret, err := node.Pkg.AsBigInt()
+		if err != nil {
+			return newError(err, -1, path)
+		}
+		fv.Set(reflect.ValueOf(*ret))
+		return nil
+	}
+
+	if fv.Kind() != reflect.Ptr && fv.CanAddr() {
+		ok, err := unmarshalNode(node, fv.Addr().Interface())
+		if err != nil {
+			return newError(err, -1, path)
+		}
+		if ok {
+			return nil
+		}
+	}
+
+	switch fv.Kind() {
+	case reflect.Ptr:
+		if fv.IsNil() {
+			fv.Set(reflect.New(fv.Type().Elem()))
+		}
+		return decodeValue(node, fv.Elem(), field, path)
+	case reflect.Bool:
+		if len(value) != 1 {
+			return newTypeMismatch(fv, len(value), path)
+		}
+		fv.SetBool(value[0]&0x01 > 0)
+		return nil
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int, reflect.Int64:
+		if field.compact {
+			ret, err := node.Pkg.AsCompactInt()
+			if err != nil || fv.OverflowInt(ret) {
+				return newTypeMismatch(fv, len(value), path)
+			}
+			fv.SetInt(ret)
+			return nil
+		}
+		//int在32位平台上可能无法表示8字节的数据
+		if validIntDigit(fv, len(value), field.varint) == false || fv.OverflowInt(signExtend(value)) {
+			return newTypeMismatch(fv, len(value), path)
+		}
+		fv.SetInt(signExtend(value))
+		return nil
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint, reflect.Uint64:
+		if field.compact {
+			ret, err := node.Pkg.AsCompactUint()
+			if err != nil || fv.OverflowUint(ret) {
+				return newTypeMismatch(fv, len(value), path)
+			}
+			fv.SetUint(ret)
+			return nil
+		}
+		if validIntDigit(fv, len(value), field.varint) == false || fv.OverflowUint(decodeUint(value)) {
+			return newTypeMismatch(fv, len(value), path)
+		}
+		fv.SetUint(decodeUint(value))
+		return nil
+	case reflect.Float32:
+		ret, err := node.Pkg.AsFloat32()
+		if err != nil {
+			return newTypeMismatch(fv, len(value), path)
+		}
+		fv.SetFloat(float64(ret))
+		return nil
+	case reflect.Float64:
+		ret, err := node.Pkg.AsFloat64()
+		if err != nil {
+			return newTypeMismatch(fv, len(value), path)
+		}
+		fv.SetFloat(ret)
+		return nil
+	case reflect.String:
+		fv.SetString(string(value))
+		return nil
+	case reflect.Struct:
+		if node.Pkg.DataType != DataTypeStruct {
+			return newTypeMismatch(fv, len(value), path)
+		}
+		return decodeStruct(node, fv, path)
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() == reflect.Uint8 {
+			fv.SetBytes(append([]byte{}, value...))
+			return nil
+		}
+		if field.packed {
+			return decodePacked(value, fv, field, path)
+		}
+	}
+
+	err := fmt.Errorf("%w: 字段%s的类型为%v", ErrUnsupportedType, field.name, fv.Type())
+	return newError(err, -1, path)
+}
+
+// 数据长度与字段类型不匹配的错误
+func newTypeMismatch(fv reflect.Value, digit int, path []int) error {
+	err := fmt.Errorf("%w: %v类型的字段不能使用%d字节的数据", ErrTypeMismatch, fv.Type(), digit)
+	return newError(err, -1, path)
+}
+
+// 检查整数的字节数，varint时可以是不超过字段位数的1/2/4/8字节
+// int及uint与平台无关，始终按8字节编码
+func validIntDigit(fv reflect.Value, digit int, varint bool) bool {
+	size := int(fv.Type().Size())
+	if fv.Kind() == reflect.Int || fv.Kind() == reflect.Uint {
+		size = 8
+	}
+	if varint == false {
+		return digit == size
+	}
+	return digit <= size && (digit == 1 || digit == 2 || digit == 4 || digit == 8)
+}
+
+// 将1/2/4/8字节的大端数据解码为无符号整数
+func decodeUint(value []byte) uint64 {
+	switch len(value) {
+	case 1:
+		return uint64(value[0])
+	case 2:
+		return uint64(binary.BigEndian.Uint16(value))
+	case 4:
+		return uint64(binary.BigEndian.Uint32(value))
+	case 8:
+		return binary.BigEndian.Uint64(value)
+	}
+	return 0
+}
+
+// 将1/2/4/8字节的大端数据按符号位扩展为有符号整数
+func signExtend(value []byte) int64 {
+	switch len(value) {
+	case 1:
+		return int64(int8(value[0]))
+	case 2:
+		return int64(int16(binary.BigEndian.Uint16(value)))
+	case 4:
+		return int64(int32(binary.BigEndian.Uint32(value)))
+	case 8:
+		return int64(binary.BigEndian.Uint64(value))
+	}
+	return 0
+}
+
+// packed切片中每个元素的字节数，不支持的类型返回0
+func packedSize(t reflect.Type) int {
+	switch t.Kind() {
+	case reflect.Int8:
+		return 1
+	case reflect.Int16, reflect.Uint16:
+		return 2
+	case reflect.Int32, reflect.Uint32, reflect.Float32:
+		return 4
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64, reflect.Float64:
+		return 8
+	}
+	return 0
+}
+
+// 将整数或浮点数切片编码为一个节点
+func encodePacked(tlvObject *TLVObject, fv reflect.Value, field *fieldInfo, path []int) error {
+	size := packedSize(fv.Type().Elem())
+	if size == 0 {
+		err := fmt.Errorf("%w: 字段%s的类型%v不能使用packed", ErrUnsupportedType, field.name, fv.Type())
+		return newError(err, -1, path)
+	}
+
+	valueBytes := make([]byte, fv.Len()*size)
+	for i := 0; i < fv.Len(); i++ {
+		elem := fv.Index(i)
+		var bits uint64
+		switch elem.Kind() {
+		case reflect.Float32:
+			bits = uint64(math.Float32bits(float32(elem.Float())))
+		case reflect.Float64:
+			bits = math.Float64bits(elem.Float())
+		case reflect.Uint16, reflect.Uint32, reflect.Uint, reflect.Uint64:
+			bits = elem.Uint()
+		default:
+			bits = uint64(elem.Int())
+		}
+		putUintBytes(valueBytes[i*size:(i+1)*size], bits)
+	}
+	return tlvObject.addPrimitiveNode(field.key, valueBytes)
+}
+
+// 按数据长度写入大端整数
+func putUintBytes(b []byte, value uint64) {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i] = byte(value)
+		value >>= 8
+	}
+}
+
+// 将一个节点解码为整数或浮点数切片
+func decodePacked(value []byte, fv reflect.Value, field *fieldInfo, path []int) error {
+	elemType := fv.Type().Elem()
+	size := packedSize(elemType)
+	if size == 0 {
+		err := fmt.Errorf("%w: 字段%s的类型%v不能使用packed", ErrUnsupportedType, field.name, fv.Type())
+		return newError(err, -1, path)
+	}
+	if len(value)%size != 0 {
+		err := fmt.Errorf("%w: %d字节不是%d字节的整数倍", ErrTypeMismatch, len(value), size)
+		return newError(err, -1, path)
+	}
+
+	slice := reflect.MakeSlice(fv.Type(), len(value)/size, len(value)/size)
+	for i := 0; i < slice.Len(); i++ {
+		elemBytes := value[i*size : (i+1)*size]
+		elem := slice.Index(i)
+		switch elemType.Kind() {
+		case reflect.Float32:
+			elem.SetFloat(float64(math.Float32frombits(binary.BigEndian.Uint32(elemBytes))))
+		case reflect.Float64:
+			elem.SetFloat(math.Float64frombits(binary.BigEndian.Uint64(elemBytes)))
+		case reflect.Uint16, reflect.Uint32, reflect.Uint, reflect.Uint64:
+			elem.SetUint(decodeUint(elemBytes))
+		default:
+			elem.SetInt(signExtend(elemBytes))
+		}
+	}
+	fv.Set(slice)
+	return nil
+}
+
+// 键值对中键及值的编码信息，使用map字段的编码选项
+func mapEntryField(field *fieldInfo, tagValue int) *fieldInfo {
+	entryField := *field
+	entryField.key = ClassTag(ClassUniversal, tagValue)
+	entryField.tagValue = tagValue
+	return &entryField
+}
+
+// 将map的每个键值对编码为一个嵌套结构
+func encodeMap(tlvObject *TLVObject, fv reflect.Value, field *fieldInfo, path []int) error {
+	keyField := mapEntryField(field, MapKeyTag)
+	valueField := mapEntryField(field, MapValueTag)
+
+	entries := make([]*TLVObject, 0, fv.Len())
+	iter := fv.MapRange()
+	for iter.Next() {
+		entry := &TLVObject{}
+		if err := encodeValue(entry, iter.Key(), keyField, path); err != nil {
+			return err
+		}
+		if err := encodeValue(entry, iter.Value(), valueField, path); err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+	}
+
+	if field.sorted {
+		sortMapEntries(entries)
+	}
+	return tlvObject.PutList(field.key, entries)
+}
+
+// 将一个键值对嵌套结构解码到map中
+func decodeMapEntry(entry *TLVObject, fv reflect.Value, field *fieldInfo, path []int) error {
+	if entry.Pkg.DataType != DataTypeStruct {
+		err := fmt.Errorf("%w: 键值对需要TLV嵌套结构", ErrTypeMismatch)
+		return newError(err, -1, path)
+	}
+
+	k := reflect.New(fv.Type().Key()).Elem()
+	keyNode, ok := entry.Get(MapKeyTag)
+	if ok == false {
+		err := fmt.Errorf("%w: 键值对缺少键", ErrNotFound)
+		return newError(err, -1, path)
+	}
+	if err := decodeValue(keyNode, k, mapEntryField(field, MapKeyTag), path); err != nil {
+		return err
+	}
+
+	v := reflect.New(fv.Type().Elem()).Elem()
+	if valueNode, ok := entry.Get(MapValueTag); ok {
+		if err := decodeValue(valueNode, v, mapEntryField(field, MapValueTag), path); err != nil {
+			return err
+		}
+	}
+	fv.SetMapIndex(k, v)
+	return nil
+}
@@ -0,0 +1,526 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golang
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testAddress struct {
+	City string `tlv:"0"`
+	Zip  uint32 `tlv:"1,omitempty"`
+}
+
+type testUser struct {
+	Id       int64          `tlv:"0"`
+	Name     string         `tlv:"1"`
+	Age      int8           `tlv:"2"`
+	Score    int32          `tlv:"3,varint"`
+	Admin    bool           `tlv:"4"`
+	Avatar   []byte         `tlv:"5,omitempty"`
+	Tags     []string       `tlv:"6"`
+	Home     testAddress    `tlv:"7"`
+	Work     *testAddress   `tlv:"8"`
+	History  []*testAddress `tlv:"9"`
+	Level    uint16         `tlv:"10,application"`
+	Nickname string         `tlv:"11,omitempty"`
+	ignored  int
+	Skipped  int `tlv:"-"`
+}
+
+// 测试结构体编码后能够还原
+func TestMarshal(t *testing.T) {
+	user := testUser{
+		Id:      -1,
+		Name:    "zhoujunhua",
+		Age:     -8,
+		Score:   -300,
+		Admin:   true,
+		Tags:    []string{"a", "", "c"},
+		Home:    testAddress{City: "sz"},
+		Work:    &testAddress{City: "gz", Zip: 510000},
+		History: []*testAddress{{City: "bj"}, {City: "sh", Zip: 200000}},
+		Level:   7,
+		Skipped: 9,
+	}
+
+	data, err := Marshal(&user)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+
+	parsed := testUser{Tags: []string{"old"}}
+	if err := Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	user.Skipped = 0
+	if reflect.DeepEqual(user, parsed) == false {
+		t.Errorf("\n%+v\n%+v", user, parsed)
+	}
+}
+
+// 测试编码结果与手动构建的TLVObject相同
+func TestMarshalMatchesPut(t *testing.T) {
+	type message struct {
+		Id   int32       `tlv:"0"`
+		Name string      `tlv:"1"`
+		Home testAddress `tlv:"2,application"`
+	}
+
+	tlvBuilder := TLVObject{}
+	tlvBuilder.PutInt32(0, 42)
+	tlvBuilder.PutString(1, "zhoujunhua")
+	home := TLVObject{}
+	tlvBuilder.Put(ClassTag(ClassApplication, 2), &home)
+	home.PutString(0, "sz")
+
+	data, err := Marshal(message{Id: 42, Name: "zhoujunhua", Home: testAddress{City: "sz"}})
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if expected := tlvBuilder.Bytes(); bytes.Equal(data, expected) == false {
+		t.Errorf("\n%x\n%x", data, expected)
+	}
+}
+
+// 测试编解码时的错误
+func TestMarshalError(t *testing.T) {
+	type badTag struct {
+		Id int32 `tlv:"x"`
+	}
+	if _, err := Marshal(badTag{}); errors.Is(err, ErrInvalidParam) == false {
+		t.Errorf("err = %v", err)
+	}
+
+	type badType struct {
+		Ch chan int `tlv:"0"`
+	}
+	if _, err := Marshal(badType{}); errors.Is(err, ErrUnsupportedType) == false {
+		t.Errorf("err = %v", err)
+	}
+
+	if err := Unmarshal(nil, testUser{}); errors.Is(err, ErrInvalidParam) == false {
+		t.Errorf("err = %v", err)
+	}
+
+	tlvBuilder := TLVObject{}
+	home := TLVObject{}
+	tlvBuilder.Put(7, &home)
+	home.PutInt16(1, 5)
+	var user testUser
+	err := Unmarshal(tlvBuilder.Bytes(), &user)
+	var tlvErr *Error
+	if errors.As(err, &tlvErr) == false || errors.Is(err, ErrTypeMismatch) == false || formatPath(tlvErr.Path) != "7/1" {
+		t.Errorf("err = %v", err)
+	}
+}
+
+// 自定义编码为嵌套结构的金额类型
+type testMoney struct {
+	currency string
+	cents    int64
+}
+
+func (this testMoney) MarshalTLV(tlvObject *TLVObject) error {
+	tlvObject.PutString(0, this.currency)
+	return tlvObject.PutInt64(1, this.cents)
+}
+
+func (this *testMoney) UnmarshalTLV(tlvObject *TLVObject) (err error) {
+	if this.currency, err = tlvObject.GetStringE(0); err != nil {
+		return err
+	}
+	this.cents, err = tlvObject.GetInt64E(1)
+	return err
+}
+
+// 通过encoding.BinaryMarshaler编码的坐标类型
+type testPoint struct {
+	x, y int16
+}
+
+func (this *testPoint) MarshalBinary() ([]byte, error) {
+	return []byte{byte(this.x >> 8), byte(this.x), byte(this.y >> 8), byte(this.y)}, nil
+}
+
+func (this *testPoint) UnmarshalBinary(data []byte) error {
+	if len(data) != 4 {
+		return ErrTypeMismatch
+	}
+	this.x = int16(data[0])<<8 | int16(data[1])
+	this.y = int16(data[2])<<8 | int16(data[3])
+	return nil
+}
+
+// 通过encoding.TextMarshaler编码的ID类型
+type testId [2]byte
+
+func (this testId) MarshalText() ([]byte, error) {
+	return []byte{'#', this[0], this[1]}, nil
+}
+
+func (this *testId) UnmarshalText(text []byte) error {
+	if len(text) != 3 || text[0] != '#' {
+		return ErrTypeMismatch
+	}
+	copy(this[:], text[1:])
+	return nil
+}
+
+// 测试反射编解码及PutMarshaler/GetUnmarshaler使用自定义编码接口
+func TestMarshaler(t *testing.T) {
+	type order struct {
+		Price  testMoney   `tlv:"0"`
+		Where  testPoint   `tlv:"1"`
+		Id     testId      `tlv:"2"`
+		Refund *testMoney  `tlv:"3"`
+		Stops  []testPoint `tlv:"4"`
+	}
+
+	src := order{
+		Price:  testMoney{"CNY", 1999},
+		Where:  testPoint{-3, 4},
+		Id:     testId{'a', 'b'},
+		Refund: &testMoney{"USD", 5},
+		Stops:  []testPoint{{1, 2}, {3, 4}},
+	}
+	data, err := Marshal(src)
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+
+	var dst order
+	if err := Unmarshal(data, &dst); err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	if reflect.DeepEqual(src, dst) == false {
+		t.Errorf("\n%+v\n%+v", src, dst)
+	}
+
+	tlvBuilder := TLVObject{}
+	tlvBuilder.PutMarshaler(0, src.Price)
+	tlvBuilder.PutMarshaler(1, &src.Where)
+	tlvBuilder.PutMarshaler(2, src.Id)
+	tlvBuilder.PutMarshaler(3, src.Refund)
+	for i := range src.Stops {
+		tlvBuilder.PutMarshaler(4, &src.Stops[i])
+	}
+	if bytes.Equal(tlvBuilder.Bytes(), data) == false {
+		t.Errorf("\n%x\n%x", tlvBuilder.Bytes(), data)
+	}
+
+	var price testMoney
+	var where testPoint
+	var id testId
+	if err := tlvBuilder.GetUnmarshaler(0, &price); err != nil || price != src.Price {
+		t.Errorf("price = %v, err = %v", price, err)
+	}
+	if err := tlvBuilder.GetUnmarshaler(1, &where); err != nil || where != src.Where {
+		t.Errorf("where = %v, err = %v", where, err)
+	}
+	if err := tlvBuilder.GetUnmarshaler(2, &id); err != nil || id != src.Id {
+		t.Errorf("id = %v, err = %v", id, err)
+	}
+	if err := tlvBuilder.GetUnmarshaler(1, &price); errors.Is(err, ErrTypeMismatch) == false {
+		t.Errorf("err = %v", err)
+	}
+	if err := tlvBuilder.PutMarshaler(5, 1); errors.Is(err, ErrUnsupportedType) == false {
+		t.Errorf("err = %v", err)
+	}
+}
+
+// 旧版本的结构体
+type testUserV1 struct {
+	Id      int32        `tlv:"1"`
+	Address *testAddress `tlv:"2"`
+	Unknown UnknownFields
+}
+
+// 测试未识别的字段在重新编码后保持原始字节
+func TestUnknownFields(t *testing.T) {
+	codec := Codec{Profile: ProfileBER}
+	data := []byte{
+		0x01, 0x04, 0x00, 0x00, 0x00, 0x07, //tag 1: int32
+		0x09, 0x81, 0x01, 0xaa, //tag 9: 非最短形式的长度
+		0x5f, 0x28, 0x02, 0x61, 0x62, //application tag 40
+	}
+
+	src := &TLVObject{}
+	if err := src.FromBytesWith(data, codec); err != nil {
+		t.Fatal(err)
+	}
+	var user testUserV1
+	if err := UnmarshalObject(src, &user); err != nil {
+		t.Fatal(err)
+	}
+	if user.Id != 7 || len(user.Unknown) != 2 {
+		t.Fatalf("user = %+v", user)
+	}
+
+	user.Id = 8
+	tlvObject, err := MarshalObject(&user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := tlvObject.BytesWith(codec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := append([]byte{0x01, 0x04, 0x00, 0x00, 0x00, 0x08}, data[6:]...)
+	if bytes.Equal(encoded, want) == false {
+		t.Errorf("\n%x\n%x", encoded, want)
+	}
+
+	var buf bytes.Buffer
+	encoder := NewEncoder(&buf)
+	encoder.Codec = codec
+	if err = encoder.Encode(tlvObject); err != nil || bytes.Equal(buf.Bytes(), want) == false {
+		t.Errorf("%x, err = %v", buf.Bytes(), err)
+	}
+
+	//再次解码时重新填充UnknownFields
+	if err = Unmarshal(data[:6], &user); err != nil || len(user.Unknown) != 0 {
+		t.Errorf("user = %+v, err = %v", user, err)
+	}
+}
+
+// 测试Put*构建的节点及编码规则不同时，未识别的字段按节点数据重新编码
+func TestUnknownFieldsBuilt(t *testing.T) {
+	child := &TLVObject{}
+	child.PutString(0, "hello")
+	var unknown UnknownFields
+	node := &TLVObject{}
+	node.Put(9, child)
+	unknown.Add(node.ChildAt(0))
+
+	tlvObject := &TLVObject{}
+	unknown.PutTo(tlvObject)
+	if encoded := tlvObject.Bytes(); bytes.Equal(encoded, []byte{0x29, 0x07, 0x00, 0x05, 'h', 'e', 'l', 'l', 'o'}) == false {
+		t.Errorf("encoded = %x", encoded)
+	}
+
+	//按BER解码的原始字节不用于ProfileLegacy的编码
+	src := &TLVObject{}
+	if err := src.FromBytesWith([]byte{0x5f, 0x28, 0x02, 0x61, 0x62}, Codec{Profile: ProfileBER}); err != nil {
+		t.Fatal(err)
+	}
+	unknown = nil
+	unknown.Add(src.ChildAt(0))
+	tlvObject = &TLVObject{}
+	unknown.PutTo(tlvObject)
+	want, _ := src.BytesWith(Codec{})
+	if encoded := tlvObject.Bytes(); bytes.Equal(encoded, want) == false {
+		t.Errorf("encoded = %x, want = %x", encoded, want)
+	}
+	if encoded, _ := tlvObject.BytesWith(Codec{Profile: ProfileBER}); bytes.Equal(encoded, []byte{0x5f, 0x28, 0x02, 0x61, 0x62}) == false {
+		t.Errorf("encoded = %x", encoded)
+	}
+}
+
+// 测试修改保存的未识别字段后按修改后的数据编码
+func TestUnknownFieldsModified(t *testing.T) {
+	tlvBuilder := TLVObject{}
+	tlvBuilder.PutInt32(1, 7)
+	inner := &TLVObject{}
+	inner.PutInt32(0, 1)
+	extra := &TLVObject{}
+	extra.Put(1, inner)
+	tlvBuilder.Put(9, extra)
+	tlvBuilder.PutString(10, "ab")
+
+	var user testUserV1
+	if err := Unmarshal(tlvBuilder.Bytes(), &user); err != nil || len(user.Unknown) != 2 {
+		t.Fatalf("user = %+v, err = %v", user, err)
+	}
+	user.Unknown[0].ChildAt(0).SetInt32(0, 2)
+	user.Unknown[1].Pkg.Value = []byte("cd")
+	user.Unknown[1].MarkDirty()
+
+	data, err := Marshal(&user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tlvParser := TLVObject{}
+	if err = tlvParser.FromBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := tlvParser.GetPathInt32("9/1/0"); v != 2 {
+		t.Errorf("v = %d", v)
+	}
+	if s, _ := tlvParser.GetString(10); s != "cd" {
+		t.Errorf("s = %q", s)
+	}
+}
+
+type testTimes struct {
+	Zero    time.Time  `tlv:"1"`
+	At      *time.Time `tlv:"2"`
+	Missing *time.Time `tlv:"3"`
+}
+
+// 测试零值时间及*time.Time字段
+func TestMarshalTimePtr(t *testing.T) {
+	at := time.Unix(1700000000, 5).UTC()
+	data, err := Marshal(testTimes{At: &at})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tlvBuilder := TLVObject{}
+	tlvBuilder.PutTime(1, time.Time{})
+	tlvBuilder.PutTime(2, at)
+	if bytes.Equal(data, tlvBuilder.Bytes()) == false {
+		t.Errorf("\n%x\n%x", data, tlvBuilder.Bytes())
+	}
+
+	var ret testTimes
+	if err = Unmarshal(data, &ret); err != nil {
+		t.Fatal(err)
+	}
+	if ret.Zero != (time.Time{}) || ret.At == nil || ret.At.Equal(at) == false || ret.Missing != nil {
+		t.Errorf("ret = %+v", ret)
+	}
+}
+
+type testNulls struct {
+	Count *int32           `tlv:"1"`
+	Flag  *bool            `tlv:"2"`
+	Name  *string          `tlv:"3"`
+	Size  int32            `tlv:"4"`
+	Attrs map[string]int32 `tlv:"5"`
+	Home  *testAddress     `tlv:"6"`
+	Tags  []string         `tlv:"7"`
+}
+
+// 测试null节点解码为零值，指针为nil
+func TestUnmarshalNull(t *testing.T) {
+	tlvBuilder := TLVObject{}
+	for key := 1; key <= 7; key++ {
+		if err := tlvBuilder.PutNull(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	count, flag, name := int32(1), true, "x"
+	ret := testNulls{Count: &count, Flag: &flag, Name: &name, Size: 3, Attrs: map[string]int32{"a": 1}}
+	if err := Unmarshal(tlvBuilder.Bytes(), &ret); err != nil {
+		t.Fatal(err)
+	}
+	//重复字段的null为一个零值元素
+	if reflect.DeepEqual(ret, testNulls{Tags: []string{""}}) == false {
+		t.Errorf("ret = %+v", ret)
+	}
+}
+
+type testSample struct {
+	Value   float64       `tlv:"1"`
+	Ratio   float32       `tlv:"2"`
+	At      time.Time     `tlv:"3"`
+	Elapsed time.Duration `tlv:"4"`
+}
+
+// 测试浮点数及时间类型与Put系列函数的编码一致
+func TestMarshalFloatTime(t *testing.T) {
+	src := testSample{Value: 2.5, Ratio: -0.25, At: time.Unix(1700000000, 5).UTC(), Elapsed: time.Minute}
+	data, err := Marshal(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tlvBuilder := TLVObject{}
+	tlvBuilder.PutFloat64(1, src.Value)
+	tlvBuilder.PutFloat32(2, src.Ratio)
+	tlvBuilder.PutTime(3, src.At)
+	tlvBuilder.PutDuration(4, src.Elapsed)
+	if bytes.Equal(tlvBuilder.Bytes(), data) == false {
+		t.Errorf("\n%x\n%x", tlvBuilder.Bytes(), data)
+	}
+
+	var dst testSample
+	if err = Unmarshal(data, &dst); err != nil || dst != src {
+		t.Errorf("dst = %+v, err = %v", dst, err)
+	}
+}
+
+type testPlatformInt struct {
+	Count int  `tlv:"1"`
+	Size  uint `tlv:"2,varint"`
+}
+
+// int及uint始终按8字节编码，与平台无关
+func TestMarshalPlatformInt(t *testing.T) {
+	src := testPlatformInt{Count: -3, Size: 70000}
+	data, err := Marshal(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tlvBuilder := TLVObject{}
+	tlvBuilder.PutInt64(1, -3)
+	tlvBuilder.PutVarUint(2, 70000)
+	if bytes.Equal(tlvBuilder.Bytes(), data) == false {
+		t.Errorf("\n%x\n%x", tlvBuilder.Bytes(), data)
+	}
+
+	var dst testPlatformInt
+	if err = Unmarshal(data, &dst); err != nil || dst != src {
+		t.Errorf("dst = %+v, err = %v", dst, err)
+	}
+}
+
+type testCounter struct {
+	Delta int32  `tlv:"1,compact"`
+	Total uint64 `tlv:"2,compact"`
+	Small int8   `tlv:"3,compact"`
+}
+
+func TestMarshalCompact(t *testing.T) {
+	src := testCounter{Delta: -2, Total: 1 << 40, Small: -128}
+	data, err := Marshal(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tlvBuilder := TLVObject{}
+	tlvBuilder.PutCompactInt(1, -2)
+	tlvBuilder.PutCompactUint(2, 1<<40)
+	tlvBuilder.PutCompactInt(3, -128)
+	if bytes.Equal(tlvBuilder.Bytes(), data) == false {
+		t.Errorf("\n%x\n%x", tlvBuilder.Bytes(), data)
+	}
+
+	var dst testCounter
+	if err = Unmarshal(data, &dst); err != nil || dst != src {
+		t.Errorf("dst = %+v, err = %v", dst, err)
+	}
+
+	//超出字段范围的数值
+	tlvBuilder = TLVObject{}
+	tlvBuilder.PutCompactInt(3, 1000)
+	if err = UnmarshalObject(&tlvBuilder, &dst); errors.Is(err, ErrTypeMismatch) == false {
+		t.Errorf("err = %v", err)
+	}
+
+	var invalid struct {
+		Value int32 `tlv:"1,varint,compact"`
+	}
+	if _, err = Marshal(invalid); errors.Is(err, ErrInvalidParam) == false {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -0,0 +1,326 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// 实现TLVObject子节点的修改功能
+package golang
+
+import (
+	"fmt"
+	"time"
+)
+
+// 节点对应的key，帧类型不是ClassUniversal时为ClassTag生成的key
+func pkgKey(pkg *TLVPkg) int {
+	if pkg.FrameType != ClassUniversal {
+		return ClassTag(pkg.FrameType, pkg.TagValue)
+	}
+	return pkg.TagValue
+}
+
+// 子节点的数量
+func (this *TLVObject) Len() int {
+	return len(this.node)
+}
+
+// 按位置获取子节点，超出范围时返回nil
+func (this *TLVObject) ChildAt(index int) *TLVObject {
+	if index < 0 || index >= len(this.node) {
+		return nil
+	}
+	return this.node[index]
+}
+
+// 在index位置之前插入一个子节点，tlvObject的帧类型及tag保持不变，index等于Len时添加到末尾
+// tlvObject已属于某个节点(如从其他对象的ChildAt获取)时会先从原位置移除，即移动节点，需要保留原节点时应先复制
+func (this *TLVObject) InsertAt(index int, tlvObject *TLVObject) error {
+	if tlvObject == nil || this.hasAncestor(tlvObject) {
+		return ErrInvalidParam
+	}
+	if index < 0 || index > len(this.node) {
+		return fmt.Errorf("%w: 位置%d超出范围[0, %d]", ErrInvalidParam, index, len(this.node))
+	}
+
+	//在当前节点内移动时，移除原位置后后面的节点前移
+	if tlvObject.parent == this {
+		if i := this.nodeIndex(tlvObject); i >= 0 && i < index {
+			index--
+		}
+	}
+	tlvObject.detach()
+	this.insertNodes(index, tlvObject)
+	return nil
+}
+
+// 子节点的位置，不是子节点时返回-1
+func (this *TLVObject) nodeIndex(child *TLVObject) int {
+	for i, node := range this.node {
+		if node == child {
+			return i
+		}
+	}
+	return -1
+}
+
+// node是否为当前节点或其祖先节点，将这样的节点添加为子节点会使parent形成环
+func (this *TLVObject) hasAncestor(node *TLVObject) bool {
+	for parent := this; parent != nil; parent = parent.parent {
+		if parent == node {
+			return true
+		}
+	}
+	return false
+}
+
+// 将节点从父节点中移除
+func (this *TLVObject) detach() {
+	parent := this.parent
+	if parent == nil {
+		return
+	}
+	this.parent = nil
+	if i := parent.nodeIndex(this); i >= 0 {
+		parent.removeAt(i)
+	}
+}
+
+// 删除index位置的子节点
+func (this *TLVObject) removeAt(index int) {
+	newNode := make([]*TLVObject, 0, len(this.node)-1)
+	newNode = append(newNode, this.node[:index]...)
+	this.node = append(newNode, this.node[index+1:]...)
+	this.dropIndex()
+	this.markDirty()
+}
+
+// 在index位置插入多个子节点
+// 修改子节点顺序的操作都使用新的切片，按值复制得到的对象与原对象共用底层数组，原位修改会破坏另一个对象
+func (this *TLVObject) insertNodes(index int, nodes ...*TLVObject) {
+	for _, node := range nodes {
+		node.parent = this
+	}
+	newNode := make([]*TLVObject, 0, len(this.node)+len(nodes))
+	newNode = append(newNode, this.node[:index]...)
+	newNode = append(newNode, nodes...)
+	this.node = append(newNode, this.node[index:]...)
+	this.dropIndex()
+	this.markDirty()
+}
+
+// 删除第一个与key匹配的子节点，没有匹配的节点时返回false
+func (this *TLVObject) Remove(key int) bool {
+	i := this.findIndex(key, 0)
+	if i < 0 {
+		return false
+	}
+	this.removeAt(i)
+	return true
+}
+
+// 删除所有与key匹配的子节点，返回删除的数量
+func (this *TLVObject) RemoveAll(key int) int {
+	var node []*TLVObject
+	for _, child := range this.node {
+		if matchKey(child.Pkg.FrameType, child.Pkg.TagValue, key) == false {
+			node = append(node, child)
+		}
+	}
+
+	count := len(this.node) - len(node)
+	if count > 0 {
+		this.node = node
+		this.dropIndex()
+		this.markDirty()
+	}
+	return count
+}
+
+// 将第一个与key匹配的子节点替换为TLV嵌套结构，保留原节点的位置及帧类型，没有匹配的节点时返回ErrNotFound
+// 与InsertAt相同，tlvObject已属于某个节点时会先从原位置移除
+func (this *TLVObject) Replace(key int, tlvObject *TLVObject) error {
+	if tlvObject != nil && this.hasAncestor(tlvObject) {
+		return ErrInvalidParam
+	}
+	i := this.findIndex(key, 0)
+	if i < 0 {
+		return newKeyError(ErrNotFound, key)
+	}
+	if tlvObject != nil && tlvObject != this.node[i] {
+		//tlvObject可能是当前节点的子节点，移除后位置会变化
+		tlvObject.detach()
+		i = this.findIndex(key, 0)
+	}
+	temp := &TLVObject{}
+	temp.Put(pkgKey(&this.node[i].Pkg), tlvObject)
+	this.replaceNode(i, temp.node[0])
+	return nil
+}
+
+// 设置key对应的节点，put向parent添加key对应的节点，如调用parent.PutBool(key, true)
+//
+// 已有与key匹配的节点时，put添加的节点替换第一个匹配节点的位置，其余的匹配节点被删除，
+// key未指定帧类型时保留原节点的帧类型；没有匹配的节点时添加到末尾
+func (this *TLVObject) SetWith(key int, put func(parent *TLVObject, key int) error) error {
+	i := this.findIndex(key, 0)
+	if i < 0 {
+		return put(this, key)
+	}
+
+	newKey := key
+	if _, _, qualified := splitKey(key); qualified == false {
+		newKey = pkgKey(&this.node[i].Pkg)
+	}
+	temp := &TLVObject{}
+	if err := put(temp, newKey); err != nil {
+		return err
+	}
+
+	this.RemoveAll(key)
+	this.insertNodes(i, temp.node...)
+	return nil
+}
+
+// 设置TLV嵌套结构，已有与key匹配的节点时替换
+// 与InsertAt相同，tlvObject已属于某个节点时会先从原位置移除
+func (this *TLVObject) Set(key int, tlvObject *TLVObject) error {
+	if tlvObject != nil {
+		if this.hasAncestor(tlvObject) {
+			return ErrInvalidParam
+		}
+		//与key匹配的子节点会被SetWith删除后重新插入
+		if tlvObject.parent != this || matchKey(tlvObject.Pkg.FrameType, tlvObject.Pkg.TagValue, key) == false {
+			tlvObject.detach()
+		}
+	}
+	return this.SetWith(key, func(parent *TLVObject, key int) error {
+		return parent.Put(key, tlvObject)
+	})
+}
+
+func (this *TLVObject) SetNull(key int) error {
+	return this.SetWith(key, (*TLVObject).PutNull)
+}
+
+func (this *TLVObject) SetBool(key int, value bool) error {
+	return this.SetWith(key, func(parent *TLVObject, key int) error {
+		return parent.PutBool(key, value)
+	})
+}
+
+func (this *TLVObject) SetInt8(key int, value int8) error {
+	return this.SetWith(key, func(parent *TLVObject, key int) error {
+		return parent.PutInt8(key, value)
+	})
+}
+
+func (this *TLVObject) SetUint8(key int, value uint8) error {
+	return this.SetWith(key, func(parent *TLVObject, key int) error {
+		return parent.PutUint8(key, value)
+	})
+}
+
+func (this *TLVObject) SetInt16(key int, value int16) error {
+	return this.SetWith(key, func(parent *TLVObject, key int) error {
+		return parent.PutInt16(key, value)
+	})
+}
+
+func (this *TLVObject) SetUint16(key int, value uint16) error {
+	return this.SetWith(key, func(parent *TLVObject, key int) error {
+		return parent.PutUint16(key, value)
+	})
+}
+
+func (this *TLVObject) SetInt32(key int, value int32) error {
+	return this.SetWith(key, func(parent *TLVObject, key int) error {
+		return parent.PutInt32(key, value)
+	})
+}
+
+func (this *TLVObject) SetUint32(key int, value uint32) error {
+	return this.SetWith(key, func(parent *TLVObject, key int) error {
+		return parent.PutUint32(key, value)
+	})
+}
+
+func (this *TLVObject) SetInt64(key int, value int64) error {
+	return this.SetWith(key, func(parent *TLVObject, key int) error {
+		return parent.PutInt64(key, value)
+	})
+}
+
+func (this *TLVObject) SetUint64(key int, value uint64) error {
+	return this.SetWith(key, func(parent *TLVObject, key int) error {
+		return parent.PutUint64(key, value)
+	})
+}
+
+func (this *TLVObject) SetVarInt(key int, value int64) error {
+	return this.SetWith(key, func(parent *TLVObject, key int) error {
+		return parent.PutVarInt(key, value)
+	})
+}
+
+func (this *TLVObject) SetVarUint(key int, value uint64) error {
+	return this.SetWith(key, func(parent *TLVObject, key int) error {
+		return parent.PutVarUint(key, value)
+	})
+}
+
+func (this *TLVObject) SetCompactInt(key int, value int64) error {
+	return this.SetWith(key, func(parent *TLVObject, key int) error {
+		return parent.PutCompactInt(key, value)
+	})
+}
+
+func (this *TLVObject) SetCompactUint(key int, value uint64) error {
+	return this.SetWith(key, func(parent *TLVObject, key int) error {
+		return parent.PutCompactUint(key, value)
+	})
+}
+
+func (this *TLVObject) SetBytes(key int, value []byte) error {
+	return this.SetWith(key, func(parent *TLVObject, key int) error {
+		return parent.PutBytes(key, value)
+	})
+}
+
+func (this *TLVObject) SetString(key int, value string) error {
+	return this.SetWith(key, func(parent *TLVObject, key int) error {
+		return parent.PutString(key, value)
+	})
+}
+
+func (this *TLVObject) SetFloat32(key int, value float32) error {
+	return this.SetWith(key, func(parent *TLVObject, key int) error {
+		return parent.PutFloat32(key, value)
+	})
+}
+
+func (this *TLVObject) SetFloat64(key int, value float64) error {
+	return this.SetWith(key, func(parent *TLVObject, key int) error {
+		return parent.PutFloat64(key, value)
+	})
+}
+
+func (this *TLVObject) SetTime(key int, value time.Time) error {
+	return this.SetWith(key, func(parent *TLVObject, key int) error {
+		return parent.PutTime(key, value)
+	})
+}
+
+func (this *TLVObject) SetDuration(key int, value time.Duration) error {
+	return this.SetWith(key, func(parent *TLVObject, key int) error {
+		return parent.PutDuration(key, value)
+	})
+}
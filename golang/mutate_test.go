@@ -0,0 +1,216 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golang
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestSet(t *testing.T) {
+	tlvObject := TLVObject{}
+	tlvObject.PutInt32(1, 1)
+	tlvObject.PutString(ClassTag(ClassApplication, 2), "a")
+	tlvObject.PutInt32(3, 3)
+	tlvObject.PutString(2, "b")
+
+	//替换第一个匹配节点并删除其余的匹配节点，保留帧类型
+	if err := tlvObject.SetString(2, "c"); err != nil {
+		t.Fatal(err)
+	}
+	if tlvObject.Len() != 3 || tlvObject.ChildAt(1).Pkg.FrameType != ClassApplication {
+		t.Fatalf("tlvObject = %v", tlvObject)
+	}
+	if values, _ := tlvObject.GetStrings(2); len(values) != 1 || values[0] != "c" {
+		t.Errorf("values = %v", values)
+	}
+
+	//重复设置不会添加新节点
+	tlvObject.SetInt32(1, 10)
+	tlvObject.SetInt32(1, 11)
+	if v, _ := tlvObject.GetInt32(1); v != 11 || tlvObject.Len() != 3 {
+		t.Errorf("v = %v, len = %d", v, tlvObject.Len())
+	}
+
+	//不存在时添加到末尾
+	tlvObject.SetNull(4)
+	if tlvObject.IsNull(4) == false || tlvObject.ChildAt(3).Pkg.TagValue != 4 {
+		t.Errorf("tlvObject = %v", tlvObject)
+	}
+
+	//添加多个节点时全部放在原位置
+	tlvObject.SetWith(1, func(parent *TLVObject, key int) error {
+		return parent.PutInt32s(key, []int32{5, 6})
+	})
+	if values, _ := tlvObject.GetInt32s(1); len(values) != 2 || tlvObject.ChildAt(1).Pkg.TagValue != 1 {
+		t.Errorf("values = %v", values)
+	}
+
+	child := &TLVObject{}
+	child.PutBool(0, true)
+	tlvObject.Set(5, child)
+	if found, ok := tlvObject.Get(5); ok == false || found != child {
+		t.Errorf("found = %v", found)
+	}
+}
+
+func TestRemoveInsert(t *testing.T) {
+	tlvObject := TLVObject{}
+	tlvObject.PutInt32s(1, []int32{1, 2, 3})
+	tlvObject.PutInt32(2, 4)
+
+	copied := tlvObject
+	if tlvObject.Remove(1) == false || tlvObject.Len() != 3 || copied.Len() != 4 || copied.ChildAt(3) == nil {
+		t.Fatalf("len = %d", tlvObject.Len())
+	}
+	if values, _ := tlvObject.GetInt32s(1); len(values) != 2 || values[0] != 2 {
+		t.Errorf("values = %v", values)
+	}
+	if count := tlvObject.RemoveAll(1); count != 2 || tlvObject.Len() != 1 {
+		t.Errorf("count = %d", count)
+	}
+	if tlvObject.Remove(1) {
+		t.Errorf("不应删除不存在的节点")
+	}
+
+	//从其他对象移动节点
+	other := TLVObject{}
+	other.PutString(3, "moved")
+	if err := tlvObject.InsertAt(0, other.ChildAt(0)); err != nil {
+		t.Fatal(err)
+	}
+	if tlvObject.ChildAt(0).Pkg.TagValue != 3 || tlvObject.ChildAt(2) != nil {
+		t.Errorf("tlvObject = %v", tlvObject)
+	}
+	if other.Len() != 0 || len(other.Bytes()) != 0 {
+		t.Errorf("移动的节点应从原对象中移除, other = %x", other.Bytes())
+	}
+	if err := tlvObject.InsertAt(3, &TLVObject{}); errors.Is(err, ErrInvalidParam) == false {
+		t.Errorf("err = %v", err)
+	}
+
+	//在同一对象内移动，index按移动前的位置计算
+	if err := tlvObject.InsertAt(2, tlvObject.ChildAt(0)); err != nil {
+		t.Fatal(err)
+	}
+	if tlvObject.Len() != 2 || tlvObject.ChildAt(1).Pkg.TagValue != 3 {
+		t.Errorf("tlvObject = %v", tlvObject)
+	}
+	if err := tlvObject.InsertAt(0, tlvObject.ChildAt(1)); err != nil || tlvObject.ChildAt(0).Pkg.TagValue != 3 {
+		t.Errorf("err = %v, tlvObject = %v", err, tlvObject)
+	}
+
+	replacement := &TLVObject{}
+	replacement.PutString(0, "inner")
+	if err := tlvObject.Replace(3, replacement); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := tlvObject.GetPathString("3/0"); s != "inner" || tlvObject.Len() != 2 {
+		t.Errorf("s = %q", s)
+	}
+	if err := tlvObject.Replace(9, replacement); errors.Is(err, ErrNotFound) == false {
+		t.Errorf("err = %v", err)
+	}
+
+	tlvParser := TLVObject{}
+	data, _ := tlvObject.BytesWith(Codec{})
+	if err := tlvParser.FromBytes(data); err != nil || tlvParser.Len() != 2 {
+		t.Errorf("tlvParser = %v, err = %v", tlvParser, err)
+	}
+}
+
+// 从解码结果及复制的对象中移动子节点
+func TestInsertFromDecoded(t *testing.T) {
+	tlvBuilder := TLVObject{}
+	inner := &TLVObject{}
+	inner.PutString(1, "a")
+	inner.PutString(2, "b")
+	tlvBuilder.Put(3, inner)
+	data := tlvBuilder.Bytes()
+
+	decoded, err := NewDecoder(bytes.NewReader(data)).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decodedInner := decoded.ChildAt(0)
+	target := TLVObject{}
+	if err = target.InsertAt(0, decodedInner.ChildAt(0)); err != nil {
+		t.Fatal(err)
+	}
+	if decodedInner.Len() != 1 || decodedInner.ChildAt(0).Pkg.TagValue != 2 {
+		t.Fatalf("decodedInner = %v", decodedInner)
+	}
+	tlvParser := TLVObject{}
+	if err = tlvParser.FromBytes(decoded.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := tlvParser.GetPathString("3/1"); ok {
+		t.Errorf("移动的节点应从解码结果中移除")
+	}
+	if s, _ := target.GetString(1); s != "a" {
+		t.Errorf("s = %q", s)
+	}
+
+	//复制的对象不拥有子节点，从中移动子节点不会破坏复制的对象
+	copied := *decodedInner
+	if err = target.InsertAt(0, copied.ChildAt(0)); err != nil {
+		t.Fatal(err)
+	}
+	if copied.Len() != 1 || copied.ChildAt(0) == nil || len(copied.Bytes()) == 0 {
+		t.Errorf("copied = %v", copied)
+	}
+}
+
+// 不能将节点自身或祖先节点添加为子节点，Set及Replace与InsertAt一样移动节点
+func TestMoveSemantics(t *testing.T) {
+	root := &TLVObject{}
+	child := &TLVObject{}
+	root.Put(1, child)
+	grandchild := &TLVObject{}
+	child.Put(2, grandchild)
+
+	if err := grandchild.InsertAt(0, root); errors.Is(err, ErrInvalidParam) == false {
+		t.Errorf("InsertAt err = %v", err)
+	}
+	if err := grandchild.Put(3, child); errors.Is(err, ErrInvalidParam) == false {
+		t.Errorf("Put err = %v", err)
+	}
+	if err := grandchild.Set(3, root); errors.Is(err, ErrInvalidParam) == false {
+		t.Errorf("Set err = %v", err)
+	}
+	if err := child.Replace(2, child); errors.Is(err, ErrInvalidParam) == false {
+		t.Errorf("Replace err = %v", err)
+	}
+
+	other := &TLVObject{}
+	other.PutInt32(5, 1)
+	other.Put(6, &TLVObject{})
+	moved, _ := other.Get(6)
+	if err := root.Replace(1, moved); err != nil {
+		t.Fatal(err)
+	}
+	if other.Len() != 1 || root.ChildAt(0) != moved || moved.Pkg.TagValue != 1 {
+		t.Errorf("Replace应移动节点, other = %v, root = %v", other, root)
+	}
+
+	moved, _ = root.Get(1)
+	if err := other.Set(5, moved); err != nil {
+		t.Fatal(err)
+	}
+	if root.Len() != 0 || other.Len() != 1 || other.ChildAt(0) != moved {
+		t.Errorf("Set应移动节点, other = %v, root = %v", other, root)
+	}
+}
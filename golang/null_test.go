@@ -0,0 +1,146 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golang
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestNullAndEmpty(t *testing.T) {
+	tlvBuilder := TLVObject{}
+	if err := tlvBuilder.PutString(1, ""); err != nil {
+		t.Fatal(err)
+	}
+	tlvBuilder.PutBytes(2, nil)
+	tlvBuilder.PutNull(3)
+	tlvBuilder.Put(4, nil)
+	tlvBuilder.PutNull(ClassTag(ClassApplication, 5))
+
+	//null编码为tag、不定长标记及结束标记
+	data := tlvBuilder.Bytes()
+	if bytes.Contains(data, []byte{0x03, 0x80, 0x00, 0x00, 0x00}) == false {
+		t.Fatalf("data = %x", data)
+	}
+
+	for _, codec := range []Codec{{}, {Profile: ProfileBER}, {Profile: ProfileBER, Canonical: true, Strict: true}} {
+		encoded, err := tlvBuilder.BytesWith(codec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		encoder := NewEncoder(&buf)
+		encoder.Codec = codec
+		if err = encoder.Encode(&tlvBuilder); err != nil || bytes.Equal(buf.Bytes(), encoded) == false {
+			t.Fatalf("encoded = %x, buf = %x, err = %v", encoded, buf.Bytes(), err)
+		}
+
+		tlvParser := TLVObject{}
+		if err = tlvParser.FromBytesWith(encoded, codec); err != nil {
+			t.Fatalf("codec = %+v, err = %v", codec, err)
+		}
+
+		//空值与null都存在，但只有null的IsNull为true
+		for key := 1; key <= 5; key++ {
+			if tlvParser.Has(key) == false {
+				t.Errorf("key %d 不存在", key)
+			}
+			if tlvParser.IsNull(key) != (key == 3 || key == 5) {
+				t.Errorf("key %d IsNull = %v", key, tlvParser.IsNull(key))
+			}
+		}
+		if tlvParser.Has(6) || tlvParser.IsNull(6) {
+			t.Errorf("key 6 应该不存在")
+		}
+
+		if s, ok := tlvParser.GetString(1); ok == false || s != "" {
+			t.Errorf("s = %q", s)
+		}
+		if _, err = tlvParser.GetStringE(3); errors.Is(err, ErrTypeMismatch) == false {
+			t.Errorf("err = %v", err)
+		}
+		if _, err = tlvParser.GetBytesE(3); errors.Is(err, ErrTypeMismatch) == false {
+			t.Errorf("err = %v", err)
+		}
+		if child, ok := tlvParser.Get(4); ok == false || len(child.Children()) != 0 {
+			t.Errorf("child = %v", child)
+		}
+		if v, err := tlvParser.GetValueE(3); v != nil || err != nil {
+			t.Errorf("v = %v, err = %v", v, err)
+		}
+	}
+
+	//流式解码同样可以识别null
+	decoder := NewDecoder(bytes.NewReader(data))
+	for key := 1; key <= 5; key++ {
+		tlvObject, err := decoder.Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tlvObject.IsNull(key) != (key == 3 || key == 5) {
+			t.Errorf("key %d IsNull = %v", key, tlvObject.IsNull(key))
+		}
+	}
+
+	//null的数据段不能有数据
+	tlvParser := TLVObject{}
+	if err := tlvParser.FromBytes([]byte{0x01, 0x80, 0x00, 0x01, 0x00, 0x00, 0x00}); errors.Is(err, ErrTypeMismatch) == false {
+		t.Errorf("err = %v", err)
+	}
+}
+
+// tag为0的空字段与结束标记相同，所在的不定长结构改用定长方式
+func TestEmptyTagZeroInIndefinite(t *testing.T) {
+	for _, codec := range []Codec{{}, {Profile: ProfileBER}} {
+		inner := &TLVObject{}
+		inner.PutString(0, "")
+		inner.PutString(2, "x")
+		root := TLVObject{}
+		root.PutIndefinite(1, inner)
+
+		encoded, err := root.BytesWith(codec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		encoder := NewEncoder(&buf)
+		encoder.Codec = codec
+		if err = encoder.Encode(&root); err != nil || bytes.Equal(buf.Bytes(), encoded) == false {
+			t.Fatalf("encoded = %x, buf = %x, err = %v", encoded, buf.Bytes(), err)
+		}
+
+		tlvParser := TLVObject{}
+		if err = tlvParser.FromBytesWith(encoded, codec); err != nil {
+			t.Fatal(err)
+		}
+		if tlvParser.Len() != 1 {
+			t.Fatalf("encoded = %x, len = %d", encoded, tlvParser.Len())
+		}
+		if s, ok := tlvParser.GetPathString("1/2"); ok == false || s != "x" {
+			t.Errorf("s = %q", s)
+		}
+		if s, ok := tlvParser.GetPathString("1/0"); ok == false || s != "" {
+			t.Errorf("s = %q", s)
+		}
+
+		encoder = NewEncoder(&buf)
+		encoder.Codec = codec
+		encoder.BeginStruct(1)
+		if err = encoder.WriteField(0, nil); errors.Is(err, ErrInvalidParam) == false {
+			t.Errorf("err = %v", err)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// 实现按路径读写嵌套的TLV节点
+package golang
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 路径中表示帧类型的前缀
+var pathClasses = map[string]Class{
+	"univ": ClassUniversal,
+	"app":  ClassApplication,
+	"ctx":  ClassContext,
+	"priv": ClassPrivate,
+}
+
+// 生成路径中的一级，与parsePath的格式相同，帧类型不是ClassUniversal时带前缀，序号为0时省略
+func formatPathSegment(key int, index int) string {
+	class, tagValue, qualified := splitKey(key)
+	ret := strconv.Itoa(tagValue)
+	if qualified {
+		for prefix, prefixClass := range pathClasses {
+			if prefixClass == class {
+				ret = prefix + ":" + ret
+				break
+			}
+		}
+	}
+	if index > 0 {
+		ret += "[" + strconv.Itoa(index) + "]"
+	}
+	return ret
+}
+
+// 路径中的一级
+type pathSegment struct {
+	key   int // 不带前缀时匹配任意帧类型
+	index int // 同一key的第几个节点，从0开始
+}
+
+// 解析路径，各级之间以/分隔，每一级的形式为[前缀:]tag[[序号]]
+// 如"1/4/2"、"1/4[3]/2"、"app:5/ctx:0"，前缀为univ、app、ctx或priv，序号从0开始，省略时为0
+func parsePath(path string) ([]pathSegment, error) {
+	if path == "" {
+		return nil, fmt.Errorf("%w: 路径为空", ErrInvalidParam)
+	}
+
+	items := strings.Split(path, "/")
+	segments := make([]pathSegment, len(items))
+	for i, item := range items {
+		segment, err := parsePathSegment(item)
+		if err != nil {
+			return nil, fmt.Errorf("%w: 路径%q的第%d级%q无效", ErrInvalidParam, path, i+1, item)
+		}
+		segments[i] = segment
+	}
+	return segments, nil
+}
+
+func parsePathSegment(item string) (segment pathSegment, err error) {
+	if pos := strings.IndexByte(item, '['); pos >= 0 {
+		if strings.HasSuffix(item, "]") == false {
+			return segment, ErrInvalidParam
+		}
+		if segment.index, err = parsePathNumber(item[pos+1 : len(item)-1]); err != nil {
+			return segment, err
+		}
+		item = item[:pos]
+	}
+
+	class, qualified := ClassUniversal, false
+	if pos := strings.IndexByte(item, ':'); pos >= 0 {
+		if class, qualified = pathClasses[item[:pos]]; qualified == false {
+			return segment, ErrInvalidParam
+		}
+		item = item[pos+1:]
+	}
+
+	tagValue, err := parsePathNumber(item)
+	if err != nil || tagValue > maxClassTagValue {
+		return segment, ErrInvalidParam
+	}
+
+	segment.key = tagValue
+	if qualified {
+		segment.key = ClassTag(class, tagValue)
+	}
+	return segment, nil
+}
+
+// 解析非负的十进制数字
+func parsePathNumber(s string) (int, error) {
+	if s == "" || strings.Trim(s, "0123456789") != "" {
+		return 0, ErrInvalidParam
+	}
+	return strconv.Atoi(s)
+}
+
+// 查找第index个与key匹配的子节点，返回其在node中的位置，不存在时返回-1
+func (this *TLVObject) findIndex(key int, index int) (ret int) {
+	ret = -1
+	this.eachMatch(key, func(i int, node *TLVObject) bool {
+		if index == 0 {
+			ret = i
+			return false
+		}
+		index--
+		return true
+	})
+	return ret
+}
+
+// 按路径获取节点，如GetPath("1/4[3]/2")
+func (this *TLVObject) GetPath(path string) (*TLVObject, bool) {
+	ret, err := this.GetPathE(path)
+	return ret, err == nil
+}
+
+// 按路径获取节点，路径无效时返回ErrInvalidParam，节点不存在时返回ErrNotFound，错误的tag路径为找到的部分及缺少的一级
+func (this *TLVObject) GetPathE(path string) (*TLVObject, error) {
+	ret, _, err := this.getPath(path)
+	return ret, err
+}
+
+// 按路径获取节点，同时返回各级的tag值
+func (this *TLVObject) getPath(path string) (*TLVObject, []int, error) {
+	segments, err := parsePath(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	current := this
+	tagPath := make([]int, 0, len(segments))
+	for _, segment := range segments {
+		_, tagValue, _ := splitKey(segment.key)
+		tagPath = append(tagPath, tagValue)
+
+		i := current.findIndex(segment.key, segment.index)
+		if i < 0 {
+			return nil, nil, newError(ErrNotFound, -1, tagPath)
+		}
+		current = current.node[i]
+	}
+	return current, tagPath, nil
+}
+
+func (this *TLVObject) GetPathInt32(path string) (int32, bool) {
+	ret, err := this.GetPathInt32E(path)
+	return ret, err == nil
+}
+
+func (this *TLVObject) GetPathInt32E(path string) (int32, error) {
+	findObject, tagPath, err := this.getPath(path)
+	if err != nil {
+		return 0, err
+	}
+	ret, err := findObject.Pkg.AsInt32()
+	return ret, pathError(err, tagPath)
+}
+
+func (this *TLVObject) GetPathString(path string) (string, bool) {
+	ret, err := this.GetPathStringE(path)
+	return ret, err == nil
+}
+
+func (this *TLVObject) GetPathStringE(path string) (string, error) {
+	findObject, tagPath, err := this.getPath(path)
+	if err != nil {
+		return "", err
+	}
+	ret, err := findObject.Pkg.AsString()
+	return ret, pathError(err, tagPath)
+}
+
+// 为错误加上完整的tag路径
+func pathError(err error, tagPath []int) error {
+	if err == nil {
+		return nil
+	}
+	var tlvErr *Error
+	if errors.As(err, &tlvErr) {
+		err = tlvErr.Err
+	}
+	return newError(err, -1, tagPath)
+}
+
+// 按路径设置TLV嵌套结构，缺少的中间节点会以TLV嵌套结构的形式创建
+// 路径的最后一级已经存在时原位置替换，否则添加到末尾，tlvObject为nil时设置为空结构
+func (this *TLVObject) SetPath(path string, tlvObject *TLVObject) error {
+	return this.SetPathWith(path, func(parent *TLVObject, key int) error {
+		return parent.Put(key, tlvObject)
+	})
+}
+
+func (this *TLVObject) SetPathInt32(path string, value int32) error {
+	return this.SetPathWith(path, func(parent *TLVObject, key int) error {
+		return parent.PutInt32(key, value)
+	})
+}
+
+func (this *TLVObject) SetPathString(path string, value string) error {
+	return this.SetPathWith(path, func(parent *TLVObject, key int) error {
+		return parent.PutString(key, value)
+	})
+}
+
+// 按路径设置节点，put向parent添加一个key对应的节点，如调用parent.PutBool(key, true)
+//
+// 中间各级不存在时创建TLV嵌套结构，序号只能等于已有的节点数，即在末尾添加；
+// 中间节点为基本数据时返回ErrTypeMismatch。替换已有节点时保留原节点的帧类型
+func (this *TLVObject) SetPathWith(path string, put func(parent *TLVObject, key int) error) error {
+	segments, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+
+	//先检查所有层级并生成新节点，确认可以设置后再修改，出错时不会留下新建的中间节点
+	current := this //最后一级的父节点，需要新建时为nil
+	index := -1     //最后一级已有节点的位置
+	tagPath := make([]int, 0, len(segments))
+	for level, segment := range segments {
+		_, tagValue, _ := splitKey(segment.key)
+		tagPath = append(tagPath, tagValue)
+
+		i, count := -1, 0
+		if current != nil {
+			i, count = current.findIndex(segment.key, segment.index), current.countKey(segment.key)
+		}
+		if i < 0 && count != segment.index {
+			return newError(fmt.Errorf("%w: 序号%d之前的节点不存在", ErrNotFound, segment.index), -1, tagPath)
+		}
+
+		if level == len(segments)-1 {
+			index = i
+			break
+		}
+		if i < 0 {
+			current = nil
+			continue
+		}
+		if current.node[i].Pkg.DataType != DataTypeStruct {
+			return newError(fmt.Errorf("%w: 中间节点不是TLV嵌套结构", ErrTypeMismatch), -1, tagPath)
+		}
+		current = current.node[i]
+	}
+
+	//替换已有节点时保留原节点的帧类型
+	key := segments[len(segments)-1].key
+	if index >= 0 {
+		key = pkgKey(&current.node[index].Pkg)
+	}
+	temp := &TLVObject{}
+	if err = put(temp, key); err != nil {
+		return pathError(err, tagPath)
+	}
+	if index >= 0 && len(temp.node) != 1 {
+		return newError(fmt.Errorf("%w: 需要添加一个节点，实际添加了%d个", ErrInvalidParam, len(temp.node)), -1, tagPath)
+	}
+
+	current = this
+	for _, segment := range segments[:len(segments)-1] {
+		i := current.findIndex(segment.key, segment.index)
+		if i < 0 {
+			child := &TLVObject{}
+			current.Put(segment.key, child)
+			current = child
+			continue
+		}
+		current = current.node[i]
+	}
+
+	if index >= 0 {
+		current.replaceNode(index, temp.node[0])
+		return nil
+	}
+	for _, node := range temp.node {
+		current.addNode(node)
+	}
+	return nil
+}
+
+// 与key匹配的子节点的数量
+func (this *TLVObject) countKey(key int) (count int) {
+	this.eachMatch(key, func(i int, node *TLVObject) bool {
+		count++
+		return true
+	})
+	return count
+}
+
+// 将第i个子节点替换为node
+func (this *TLVObject) replaceNode(i int, node *TLVObject) {
+	node.parent = this
+	this.node[i] = node
+	//新节点的tag可能不同
+	this.dropIndex()
+	this.markDirty()
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golang
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGetPath(t *testing.T) {
+	tlvBuilder := TLVObject{}
+	outer := &TLVObject{}
+	for i := int32(0); i < 3; i++ {
+		item := &TLVObject{}
+		item.PutInt32(2, i*10)
+		outer.Put(4, item)
+	}
+	outer.PutString(ClassTag(ClassContext, 0), "ctx")
+	tlvBuilder.Put(1, outer)
+
+	tlvParser := TLVObject{}
+	codec := Codec{Profile: ProfileBER}
+	data, err := tlvBuilder.BytesWith(codec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = tlvParser.FromBytesWith(data, codec); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, ok := tlvParser.GetPathInt32("1/4/2"); ok == false || v != 0 {
+		t.Errorf("v = %v", v)
+	}
+	if v, ok := tlvParser.GetPathInt32("1/4[2]/2"); ok == false || v != 20 {
+		t.Errorf("v = %v", v)
+	}
+	if s, ok := tlvParser.GetPathString("1/ctx:0"); ok == false || s != "ctx" {
+		t.Errorf("s = %q", s)
+	}
+	if _, ok := tlvParser.GetPath("1/app:0"); ok {
+		t.Errorf("帧类型不同的节点不应匹配")
+	}
+
+	_, err = tlvParser.GetPathInt32E("1/4[3]/2")
+	if errors.Is(err, ErrNotFound) == false || formatPath(err.(*Error).Path) != "1/4" {
+		t.Errorf("err = %v", err)
+	}
+	_, err = tlvParser.GetPathInt32E("1/ctx:0")
+	if errors.Is(err, ErrTypeMismatch) == false || formatPath(err.(*Error).Path) != "1/0" {
+		t.Errorf("err = %v", err)
+	}
+
+	for _, path := range []string{"", "1//2", "a", "1/4[", "1/4[x]", "-1", "foo:1", "1/4[1]x", "268435456"} {
+		if _, err = tlvParser.GetPathE(path); errors.Is(err, ErrInvalidParam) == false {
+			t.Errorf("path = %q, err = %v", path, err)
+		}
+	}
+}
+
+func TestSetPath(t *testing.T) {
+	tlvObject := TLVObject{}
+	if err := tlvObject.SetPathInt32("1/4/2", 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := tlvObject.SetPathString("1/4[1]/app:3", "second"); err != nil {
+		t.Fatal(err)
+	}
+	//已存在的节点原位置替换
+	if err := tlvObject.SetPathInt32("1/4/2", 6); err != nil {
+		t.Fatal(err)
+	}
+	if err := tlvObject.SetPath("1/4[1]/7", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tlvParser := TLVObject{}
+	if err := tlvParser.FromBytes(tlvObject.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := tlvParser.GetPathInt32("1/4/2"); v != 6 {
+		t.Errorf("v = %v", v)
+	}
+	if s, _ := tlvParser.GetPathString("1/4[1]/app:3"); s != "second" {
+		t.Errorf("s = %q", s)
+	}
+	if outer, _ := tlvParser.GetPath("1/4"); len(outer.Children()) != 1 {
+		t.Errorf("outer = %v", outer)
+	}
+	if child, ok := tlvParser.GetPath("1/4[1]/7"); ok == false || child.Pkg.DataType != DataTypeStruct {
+		t.Errorf("child = %v", child)
+	}
+
+	//替换时保留原节点的帧类型
+	tlvObject.SetPathString("1/4[1]/3", "third")
+	if s, _ := tlvObject.GetPathString("1/4[1]/app:3"); s != "third" {
+		t.Errorf("s = %q", s)
+	}
+
+	err := tlvObject.SetPathInt32("1/4[3]/2", 1)
+	if errors.Is(err, ErrNotFound) == false || formatPath(err.(*Error).Path) != "1/4" {
+		t.Errorf("err = %v", err)
+	}
+	err = tlvObject.SetPathInt32("1/4/2/1", 1)
+	if errors.Is(err, ErrTypeMismatch) == false || formatPath(err.(*Error).Path) != "1/4/2" {
+		t.Errorf("err = %v", err)
+	}
+
+	//出错时不留下新建的中间节点
+	empty := TLVObject{}
+	err = empty.SetPathInt32("1/2/3[5]", 1)
+	if errors.Is(err, ErrNotFound) == false || formatPath(err.(*Error).Path) != "1/2/3" || empty.Has(1) {
+		t.Errorf("err = %v, len = %d", err, empty.Len())
+	}
+	err = empty.SetPathWith("1/2", func(parent *TLVObject, key int) error {
+		return parent.PutTime(key, time.Date(1600, 1, 1, 0, 0, 0, 0, time.UTC))
+	})
+	if errors.Is(err, ErrInvalidParam) == false || empty.Len() != 0 {
+		t.Errorf("err = %v, len = %d", err, empty.Len())
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// 声明TLV嵌套结构的字段，并校验TLVObject是否符合声明
+package golang
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnknownTag   = errors.New("未声明的字段")
+	ErrDuplicateTag = errors.New("非重复字段出现多次")
+	ErrOutOfRange   = errors.New("字段长度超出范围")
+)
+
+// 字段的数据类型
+type Kind int
+
+const (
+	KindAny     Kind = iota // 不检查数据类型
+	KindBool                // 1字节bool
+	KindInt8                // 1字节整数
+	KindInt16               // 2字节整数
+	KindInt32               // 4字节整数
+	KindInt64               // 8字节整数
+	KindVarInt              // PutVarInt/PutVarUint写入的1/2/4/8字节整数
+	KindString              // 字符串
+	KindBytes               // 字节数组
+	KindStruct              // TLV嵌套结构
+	KindCompact             // PutCompactInt/PutCompactUint写入的整数
+)
+
+var kindNames = [...]string{"any", "bool", "int8", "int16", "int32", "int64", "varint", "string", "bytes", "struct", "compact"}
+
+func (this Kind) String() string {
+	if this >= 0 && int(this) < len(kindNames) {
+		return kindNames[this]
+	}
+	return fmt.Sprintf("Kind(%d)", int(this))
+}
+
+// 整数类型的数据长度，有符号及无符号整数使用相同的Kind
+func (this Kind) digit() int {
+	switch this {
+	case KindBool, KindInt8:
+		return 1
+	case KindInt16:
+		return 2
+	case KindInt32:
+		return 4
+	case KindInt64:
+		return 8
+	}
+	return 0
+}
+
+// TLV嵌套结构的声明
+type Schema struct {
+	Fields []FieldSchema
+
+	// 为true时未声明的tag作为错误，否则忽略
+	Strict bool
+}
+
+// 一个字段的声明
+type FieldSchema struct {
+	Key      int    // 字段的tag，可以使用ClassTag限定帧类型
+	Name     string // 字段名称，用于错误信息
+	Kind     Kind
+	Required bool // 是否必须存在
+	Repeated bool // 是否允许出现多次
+
+	// 数据段的字节数范围，为0时不限制，对KindStruct无效
+	MinSize int
+	MaxSize int
+
+	// Kind为KindStruct时子节点的声明，为nil时不检查子节点
+	Schema *Schema
+}
+
+// 按声明校验tlvObject的子节点，返回所有不符合声明的错误，没有错误时返回nil
+func (this *Schema) Validate(tlvObject *TLVObject) []*Error {
+	return this.validate(tlvObject, nil, nil)
+}
+
+func (this *Schema) validate(tlvObject *TLVObject, path []int, errs []*Error) []*Error {
+	counts := make([]int, len(this.Fields))
+	for _, child := range tlvObject.node {
+		childPath := append(path[:len(path):len(path)], child.Pkg.TagValue)
+
+		index := this.lookup(child)
+		if index < 0 {
+			if this.Strict {
+				errs = append(errs, newError(ErrUnknownTag, -1, childPath))
+			}
+			continue
+		}
+
+		field := &this.Fields[index]
+		counts[index]++
+		if counts[index] == 2 && field.Repeated == false {
+			errs = append(errs, newError(fmt.Errorf("%w: %s", ErrDuplicateTag, field.Name), -1, childPath))
+		}
+		errs = field.validate(child, childPath, errs)
+	}
+
+	for i := range this.Fields {
+		field := &this.Fields[i]
+		if field.Required && counts[i] == 0 {
+			_, tagValue, _ := splitKey(field.Key)
+			childPath := append(path[:len(path):len(path)], tagValue)
+			errs = append(errs, newError(fmt.Errorf("%w: 缺少必需字段%s", ErrNotFound, field.Name), -1, childPath))
+		}
+	}
+	return errs
+}
+
+// 查找子节点对应的字段声明
+func (this *Schema) lookup(child *TLVObject) int {
+	for i := range this.Fields {
+		if matchKey(child.Pkg.FrameType, child.Pkg.TagValue, this.Fields[i].Key) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (this *FieldSchema) validate(node *TLVObject, path []int, errs []*Error) []*Error {
+	if this.Kind == KindAny {
+		return errs
+	}
+
+	if (this.Kind == KindStruct) != (node.Pkg.DataType == DataTypeStruct) {
+		return append(errs, newError(fmt.Errorf("%w: %s需要%v类型", ErrTypeMismatch, this.Name, this.Kind), -1, path))
+	}
+	if this.Kind == KindStruct {
+		if this.Schema != nil {
+			errs = this.Schema.validate(node, path, errs)
+		}
+		return errs
+	}
+
+	size := len(node.Pkg.Value)
+	if digit := this.Kind.digit(); digit > 0 && size != digit {
+		return append(errs, newError(fmt.Errorf("%w: %s需要%d字节, 实际为%d字节", ErrTypeMismatch, this.Name, digit, size), -1, path))
+	}
+	if this.Kind == KindVarInt && size != 1 && size != 2 && size != 4 && size != 8 {
+		return append(errs, newError(fmt.Errorf("%w: %s整数不能为%d字节", ErrTypeMismatch, this.Name, size), -1, path))
+	}
+	if this.Kind == KindCompact {
+		if _, err := node.Pkg.AsCompactUint(); err != nil {
+			return append(errs, newError(fmt.Errorf("%w: %s", err, this.Name), -1, path))
+		}
+	}
+	if (this.MinSize > 0 && size < this.MinSize) || (this.MaxSize > 0 && size > this.MaxSize) {
+		errs = append(errs, newError(fmt.Errorf("%w: %s为%d字节", ErrOutOfRange, this.Name, size), -1, path))
+	}
+	return errs
+}
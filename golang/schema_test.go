@@ -0,0 +1,92 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golang
+
+import (
+	"errors"
+	"testing"
+)
+
+var testSchema = &Schema{
+	Strict: true,
+	Fields: []FieldSchema{
+		{Key: 1, Name: "id", Kind: KindInt64, Required: true},
+		{Key: 2, Name: "name", Kind: KindString, Required: true, MinSize: 1, MaxSize: 8},
+		{Key: 3, Name: "tags", Kind: KindString, Repeated: true},
+		{Key: 5, Name: "delta", Kind: KindCompact},
+		{Key: 4, Name: "address", Kind: KindStruct, Schema: &Schema{
+			Fields: []FieldSchema{
+				{Key: 1, Name: "city", Kind: KindString, Required: true},
+				{Key: ClassTag(ClassApplication, 2), Name: "zip", Kind: KindVarInt},
+			},
+		}},
+	},
+}
+
+func TestSchemaValidate(t *testing.T) {
+	address := &TLVObject{}
+	address.PutString(1, "shenzhen")
+	address.PutVarUint(ClassTag(ClassApplication, 2), 518000)
+
+	tlvObject := &TLVObject{}
+	tlvObject.PutInt64(1, 7)
+	tlvObject.PutString(2, "alice")
+	tlvObject.PutString(3, "a")
+	tlvObject.PutString(3, "b")
+	tlvObject.Put(4, address)
+	tlvObject.PutCompactInt(5, -1000)
+	if errs := testSchema.Validate(tlvObject); errs != nil {
+		t.Errorf("errs = %v", errs)
+	}
+
+	//未限定帧类型的节点不匹配限定了帧类型的声明，非Strict时忽略
+	address.PutVarUint(2, 1)
+	if errs := testSchema.Validate(tlvObject); errs != nil {
+		t.Errorf("errs = %v", errs)
+	}
+}
+
+func TestSchemaViolations(t *testing.T) {
+	address := &TLVObject{}
+	address.PutInt32(5, 1)
+
+	tlvObject := &TLVObject{}
+	tlvObject.PutInt32(1, 7)
+	tlvObject.PutString(2, "too long name")
+	tlvObject.PutString(2, "bob")
+	tlvObject.Put(4, address)
+	tlvObject.PutBool(9, true)
+
+	want := []struct {
+		err  error
+		path string
+	}{
+		{ErrTypeMismatch, "1"},
+		{ErrOutOfRange, "2"},
+		{ErrDuplicateTag, "2"},
+		{ErrNotFound, "4/1"},
+		{ErrUnknownTag, "9"},
+	}
+
+	errs := testSchema.Validate(tlvObject)
+	if len(errs) != len(want) {
+		t.Fatalf("errs = %v", errs)
+	}
+	for i, w := range want {
+		if errors.Is(errs[i], w.err) == false || formatPath(errs[i].Path) != w.path {
+			t.Errorf("errs[%d] = %v, 需要%v, tag路径: %s", i, errs[i], w.err, w.path)
+		}
+	}
+}
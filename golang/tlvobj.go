@@ -19,6 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"time"
 )
 
 var (
@@ -26,15 +27,104 @@ var (
 )
 
 // TLV构建对象
+// 子节点的编码结果会被缓存，通过Put*、Set*等方法修改时缓存自动失效；
+// 直接修改Pkg等导出字段后需要调用MarkDirty，否则Bytes可能返回修改前的编码结果
 type TLVObject struct {
 	Pkg TLVPkg
 
-	node []*TLVObject //该tlv结构下的数据
+	node     []*TLVObject //该tlv结构下的数据
+	raw      []byte       //编码规则与rawCodec相符时原样输出，用于保留未识别的字段
+	rawCodec Codec        //raw使用的编码规则，即解码时的编码规则
+	source   *Codec       //解码得到的节点所使用的编码规则，Put*添加的节点为nil
+
+	parent     *TLVObject //所属的父节点，子节点修改时通过它使祖先节点的缓存失效
+	cache      []byte     //子节点的编码结果，即TLV嵌套结构的数据段，为nil时需要重新编码
+	cacheCodec Codec      //cache使用的编码规则，Strict为true时表示cache同时是规范形式
+
+	index      map[int][]int //tag值到子节点位置的索引，见lookupIndex
+	indexOwner *TLVObject    //建立index时的地址，按值复制后与实际地址不同，此时不使用index
 }
 
 // 添加一个TLV对象
 func (this *TLVObject) addNode(node *TLVObject) {
+	node.parent = this
 	this.node = append(this.node, node)
+	this.indexAppend(node)
+	this.markDirty()
+}
+
+// 添加解析得到的子节点，解析过程中不逐层清除祖先节点的缓存，由FromBytesWith统一清除
+func (this *TLVObject) addParsedNode(node *TLVObject) {
+	node.parent = this
+	this.node = append(this.node, node)
+	this.indexAppend(node)
+}
+
+// 直接修改了节点的导出字段(如Pkg.Value)后调用，使该节点及所有祖先节点重新编码
+// 修改Pkg.TagValue后父节点的子节点索引同样需要重建
+func (this *TLVObject) MarkDirty() {
+	this.dropIndex()
+	if this.parent != nil {
+		this.parent.dropIndex()
+	}
+	this.markDirty()
+}
+
+// 子节点发生变化，清除该节点及所有祖先节点的编码缓存及原始字节
+func (this *TLVObject) markDirty() {
+	for node := this; node != nil; node = node.parent {
+		node.cache = nil
+		node.raw = nil
+	}
+}
+
+// 将子节点的parent指向当前对象，只能在原对象(如按值复制前的对象)不再使用时调用
+func (this *TLVObject) adoptChildren() {
+	for _, node := range this.node {
+		node.parent = this
+	}
+}
+
+// 缓存的编码结果能否用于codec
+func (this *TLVObject) cacheValid(codec Codec) bool {
+	return this.cache != nil && this.cacheCodec.Profile == codec.Profile &&
+		(this.cacheCodec.Canonical == codec.Canonical || this.cacheCodec.Strict) && this.ownsChildren()
+}
+
+// 原始字节能否用于codec，不能使用时按Pkg及子节点重新编码
+func (this *TLVObject) rawValid(codec Codec) bool {
+	return this.raw != nil && this.rawCodec.Profile == codec.Profile &&
+		(codec.Canonical == false || this.rawCodec.Strict)
+}
+
+// 子节点的parent是否都指向当前对象
+// 按值复制得到的对象与原对象共用子节点，子节点的parent仍指向原对象，修改子节点不会使复制对象的缓存失效，
+// 因此复制对象不使用缓存
+func (this *TLVObject) ownsChildren() bool {
+	for _, node := range this.node {
+		if node.parent != this {
+			return false
+		}
+	}
+	return true
+}
+
+// 获取子节点的编码结果，未修改过的子树直接使用缓存
+func (this *TLVObject) encodeValue(codec Codec) ([]byte, error) {
+	if this.cacheValid(codec) {
+		return this.cache, nil
+	}
+
+	value, err := buildNode(this.node, codec)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	this.cache = value
+	this.cacheCodec = Codec{Profile: codec.Profile, Canonical: codec.Canonical}
+	return value, nil
 }
 
 func (this TLVObject) String() (ret string) {
@@ -52,78 +142,166 @@ func traversalField(node []*TLVObject) (ret string) {
 }
 
 // 通过二进制字节，得到TLV对象
-func (this *TLVObject) FromBytes(tlvBytes []byte) {
-	parseTLVPkg(this, tlvBytes)
+func (this *TLVObject) FromBytes(tlvBytes []byte) error {
+	return this.FromBytesWith(tlvBytes, Codec{})
+}
+
+// 按指定的编码规则解析二进制字节，得到TLV对象
+// 解析得到的节点以原始字节作为编码缓存，使用相同的编码规则重新编码时，未修改的子树原样输出
+func (this *TLVObject) FromBytesWith(tlvBytes []byte, codec Codec) error {
+	empty := len(this.node) == 0
+	this.markDirty()
+	for offset := 0; offset < len(tlvBytes); {
+		consumeLen, err := parseTLVPkg(this, tlvBytes[offset:], offset, nil, codec)
+		if err != nil {
+			return err
+		}
+		offset += consumeLen
+	}
+	if empty {
+		this.setParsedCache(tlvBytes, codec)
+	}
+	return nil
 }
 
-// 解析出TLV对象
-func parseTLVPkg(node *TLVObject, tlvBytes []byte) {
+// 以解码时的原始字节作为编码缓存
+// 非Strict解码时原始字节可能不是规范形式，只能用于相同编码规则的非规范形式编码
+func (this *TLVObject) setParsedCache(value []byte, codec Codec) {
+	if value == nil {
+		value = []byte{}
+	}
+	this.cache = value
+	this.cacheCodec = Codec{Profile: codec.Profile, Canonical: codec.Strict, Strict: codec.Strict}
+}
 
-	tagByteCount := findTagByteCount(tlvBytes)
-	lenByteCount := findLenByteCount(tlvBytes, tagByteCount)
-	length := parseLength(tlvBytes[tagByteCount : tagByteCount+lenByteCount])
+// 解析出TLV对象，返回该TLV包占用的字节数
+// offset为该TLV包在整个数据中的偏移，path为父节点的tag路径，用于生成错误信息
+// 不定长方式的数据段在逐个解析子包时确定，每个字节只解析一次
+func parseTLVPkg(node *TLVObject, tlvBytes []byte, offset int, path []int, codec Codec) (consumeLen int, err error) {
 
-	//fmt.Printf("tagByteCount = %v, lenByteCount = %v, length = %v\n", tagByteCount, lenByteCount, length)
+	pkg := TLVPkg{}
+	ok, err := codec.parseTagLength(tlvBytes, &pkg)
+	if err != nil {
+		return 0, newError(err, offset, path)
+	}
+	if ok == false {
+		return 0, newError(ErrTruncated, offset, path)
+	}
 
-	var value []byte
-	frameType, dataType, tagValue := parseTag(tlvBytes[:tagByteCount])
-	value = tlvBytes[tagByteCount+lenByteCount : tagByteCount+lenByteCount+length]
+	//fmt.Printf("frameType = %v, dataType = %v, tagValue = %v, value = %v\n", pkg.FrameType, pkg.DataType, pkg.TagValue, pkg.Value)
 
-	//fmt.Printf("frameType = %v, dataType = %v, tagValue = %v, value = %v\n", frameType, dataType, tagValue, value)
+	parentPath := path
+	path = append(path, pkg.TagValue)
 
-	pkg := TLVPkg{
-		FrameType: frameType,
-		DataType:  dataType,
-		TagValue:  tagValue,
-		Value:     value,
+	newNode := &TLVObject{
+		Pkg:    pkg,
+		source: &codec,
 	}
+	node.addParsedNode(newNode)
 
-	newNode := TLVObject{
-		Pkg: pkg,
+	if codec.Strict {
+		if err = checkCanonicalOrder(node.node); err != nil {
+			return 0, newError(err, offset, path)
+		}
 	}
-	node.addNode(&newNode)
-
-	if dataType == DataTypeStruct {
-		tlvBytes = tlvBytes[tagByteCount+lenByteCount:]
-		remainLen := len(tlvBytes)
-		offset := 0
 
+	if pkg.DataType == DataTypeStruct {
+		headLen := pkg.tagByteCount + pkg.lenByteCount
+		value := pkg.Value
+		if pkg.Indefinite {
+			value = tlvBytes[headLen:]
+		}
+		childOffset := 0
 		for {
-			if remainLen <= 0 {
-				//fmt.Printf("exit, remainLen = %v\n", remainLen)
+			if pkg.Indefinite {
+				if len(value)-childOffset < len(endOfContents) {
+					return 0, newError(ErrTruncated, offset, parentPath)
+				}
+				if startsWithEndOfContents(value[childOffset:]) {
+					break
+				}
+			} else if childOffset >= len(value) {
 				break
 			}
+			consumeLen, err := parseTLVPkg(newNode, value[childOffset:], offset+headLen+childOffset, path, codec)
+			if err != nil {
+				return 0, err
+			}
+			childOffset += consumeLen
+		}
+		if pkg.Indefinite {
+			newNode.Pkg.Value = value[:childOffset]
+			newNode.Pkg.dataByteCount = childOffset + len(endOfContents)
+		}
+		newNode.setParsedCache(newNode.Pkg.Value, codec)
+	}
+	newNode.Pkg.setData(tlvBytes[:newNode.Pkg.Size()], codec.Profile)
 
-			tagByteCount := findTagByteCount(tlvBytes[offset:])
-			lenByteCount := findLenByteCount(tlvBytes[offset:], tagByteCount)
-			length := parseLength(tlvBytes[offset+tagByteCount : offset+tagByteCount+lenByteCount])
-
-			consumeLen := tagByteCount + lenByteCount + length
+	return newNode.Pkg.Size(), nil
+}
 
-			parseTLVPkg(&newNode, tlvBytes[offset:offset+consumeLen])
+// 带帧类型的key为负数，由帧类型及tag值组合后按位取反得到，帧类型位于第22位起，tag值占用低28位
+// 非负的key即为不带帧类型的tag值，两者不会混淆
+const (
+	classKeyShift    = 22
+	maxClassTagValue = 1<<28 - 1
+	minClassKey      = ^(int(ClassPrivate)<<classKeyShift | maxClassTagValue)
+	invalidKey       = minClassKey - 1
+)
 
-			//fmt.Printf("remainLen = %v, consumeLen = %v\n", remainLen, consumeLen)
+// 生成带帧类型的key，可用于Get*及Put*系列方法
+// 不带帧类型的key在查找时匹配任意帧类型，添加时使用ClassUniversal
+// tagValue超出0至2^28-1的范围或class无效时返回无效的key，使用该key添加节点会返回ErrInvalidParam
+func ClassTag(class Class, tagValue int) int {
+	if tagValue < 0 || tagValue > maxClassTagValue || class&^ClassPrivate != 0 {
+		return invalidKey
+	}
+	return ^(int(class)<<classKeyShift | tagValue)
+}
 
-			offset += consumeLen
-			remainLen -= consumeLen
-		}
+// 拆分key，得到帧类型及tag值，qualified表示key是否带有帧类型
+// 无效的key得到的tag值为-1，不会与任何节点匹配
+func splitKey(key int) (class Class, tagValue int, qualified bool) {
+	if key >= 0 {
+		return ClassUniversal, key, false
+	}
+	if key < minClassKey {
+		return ClassUniversal, -1, true
+	}
+	key = ^key
+	return Class(key>>classKeyShift) & ClassPrivate, key & maxClassTagValue, true
+}
 
+// 检查key能否用于添加节点
+func checkKey(key int) error {
+	if key > maxClassTagValue {
+		return fmt.Errorf("%w: tag值%d超过%d", ErrTagOverflow, key, maxClassTagValue)
+	}
+	if key < minClassKey {
+		return fmt.Errorf("%w: 无效的key %d", ErrInvalidParam, key)
 	}
+	return nil
+}
+
+// 判断节点的帧类型及tag值是否与key匹配
+func matchKey(frameType Class, tagValue int, key int) bool {
+	class, keyTagValue, qualified := splitKey(key)
+	return tagValue == keyTagValue && (qualified == false || frameType == class)
 }
 
 func findTLVObject(rawObject *TLVObject, key int) (retObject *TLVObject, ok bool) {
-	ok = false
-	for i := 0; i < len(rawObject.node); i++ {
-		tagValue := rawObject.node[i].Pkg.TagValue
-		if tagValue == key {
-			retObject = rawObject.node[i]
-			ok = true
-			break
-		}
-	}
+	rawObject.eachMatch(key, func(i int, node *TLVObject) bool {
+		retObject, ok = node, true
+		return false
+	})
 	return retObject, ok
 }
 
+// 获取TLVObject下的所有子节点，返回的切片不应修改
+func (this *TLVObject) Children() []*TLVObject {
+	return this.node
+}
+
 // 获取TLVObject的key
 func (this *TLVObject) GetKey() int {
 	return this.node[0].Pkg.TagValue
@@ -135,207 +313,336 @@ func (this *TLVObject) Get(key int) (tlvObject *TLVObject, ok bool) {
 	return findObject, ok
 }
 
-func (this *TLVObject) GetBool(key int) (ret bool, ok bool) {
+// 是否存在key对应的节点，值为null或空值的节点同样视为存在
+func (this *TLVObject) Has(key int) bool {
+	_, ok := findTLVObject(this, key)
+	return ok
+}
+
+// key对应的节点是否为PutNull添加的null，节点不存在时返回false
+func (this *TLVObject) IsNull(key int) bool {
+	findObject, ok := findTLVObject(this, key)
+	return ok && findObject.Pkg.IsNull()
+}
+
+// 获取TLVObject下的一个TLVObject，不存在时返回ErrNotFound
+func (this *TLVObject) GetE(key int) (*TLVObject, error) {
 	findObject, ok := findTLVObject(this, key)
 	if ok == false {
-		//fmt.Printf("don't exist this field, key:%v\n", key)
-		return false, false
+		return nil, newKeyError(ErrNotFound, key)
 	}
+	return findObject, nil
+}
 
-	value := findObject.Pkg.Value
-	if len(value) != 1 {
-		fmt.Printf("该字段不是bool类型, key:%v, value:%v\n", key, value)
-		return false, false
+// 查找基本数据节点，出错时返回带有tag路径的错误
+func (this *TLVObject) getPkgE(key int) (*TLVPkg, error) {
+	findObject, err := this.GetE(key)
+	if err != nil {
+		return nil, err
 	}
+	return &findObject.Pkg, nil
+}
 
-	if value[0]&0x01 > 0 {
-		ret = true
-	} else {
-		ret = false
+// 为解码节点数据时的错误加上tag路径
+func keyError(err error, key int) error {
+	if err != nil {
+		return newKeyError(err, key)
 	}
+	return nil
+}
 
-	return ret, false
+func (this *TLVObject) GetBool(key int) (ret bool, ok bool) {
+	ret, err := this.GetBoolE(key)
+	return ret, err == nil
 }
 
-func (this *TLVObject) GetInt8(key int) (ret int8, ok bool) {
-	findObject, ok := findTLVObject(this, key)
-	if ok == false {
-		//fmt.Printf("don't exist this field, key:%v\n", key)
-		return 0, false
+func (this *TLVObject) GetBoolE(key int) (bool, error) {
+	pkg, err := this.getPkgE(key)
+	if err != nil {
+		return false, err
 	}
+	ret, err := pkg.AsBool()
+	return ret, keyError(err, key)
+}
 
-	value := findObject.Pkg.Value
-	if len(value) != 1 {
-		fmt.Printf("该字段不是int8类型, key:%v, value:%v\n", key, value)
-		return 0, false
-	}
+func (this *TLVObject) GetInt8(key int) (ret int8, ok bool) {
+	ret, err := this.GetInt8E(key)
+	return ret, err == nil
+}
 
-	return int8(value[0]), true
+func (this *TLVObject) GetInt8E(key int) (int8, error) {
+	ret, err := this.GetUint8E(key)
+	return int8(ret), err
 }
 
 func (this *TLVObject) GetUint8(key int) (ret uint8, ok bool) {
-	findObject, ok := findTLVObject(this, key)
-	if ok == false {
-		//fmt.Printf("don't exist this field, key:%v\n", key)
-		return 0, false
+	ret, err := this.GetUint8E(key)
+	return ret, err == nil
+}
+
+func (this *TLVObject) GetUint8E(key int) (uint8, error) {
+	pkg, err := this.getPkgE(key)
+	if err != nil {
+		return 0, err
 	}
+	ret, err := pkg.AsUint8()
+	return ret, keyError(err, key)
+}
+
+func (this *TLVObject) GetInt16(key int) (int16, bool) {
+	ret, err := this.GetUint16E(key)
+	return int16(ret), err == nil
+}
+
+func (this *TLVObject) GetInt16E(key int) (int16, error) {
+	ret, err := this.GetUint16E(key)
+	return int16(ret), err
+}
+
+func (this *TLVObject) GetInt32(key int) (int32, bool) {
+	ret, err := this.GetUint32E(key)
+	return int32(ret), err == nil
+}
 
-	value := findObject.Pkg.Value
-	if len(value) != 1 {
-		fmt.Printf("该字段不是int8类型, key:%v, value:%v\n", key, value)
-		return 0, false
+func (this *TLVObject) GetInt32E(key int) (int32, error) {
+	ret, err := this.GetUint32E(key)
+	return int32(ret), err
+}
+
+func (this *TLVObject) GetInt64(key int) (int64, bool) {
+	ret, err := this.GetUint64E(key)
+	return int64(ret), err == nil
+}
+
+func (this *TLVObject) GetInt64E(key int) (int64, error) {
+	ret, err := this.GetUint64E(key)
+	return int64(ret), err
+}
+
+func (this *TLVObject) GetUint16(key int) (uint16, bool) {
+	ret, err := this.GetUint16E(key)
+	return ret, err == nil
+}
+
+func (this *TLVObject) GetUint16E(key int) (uint16, error) {
+	pkg, err := this.getPkgE(key)
+	if err != nil {
+		return 0, err
 	}
+	ret, err := pkg.AsUint16()
+	return ret, keyError(err, key)
+}
 
-	return uint8(value[0]), true
+func (this *TLVObject) GetUint32(key int) (uint32, bool) {
+	ret, err := this.GetUint32E(key)
+	return ret, err == nil
 }
 
-// 获取指定位数
-func (this *TLVObject) getIntWithDigit(key int, digit int) (ret int64, ok bool) {
-	findObject, ok := findTLVObject(this, key)
-	if ok == false {
-		//fmt.Printf("don't exist this field, key:%v\n", key)
-		return 0, false
+func (this *TLVObject) GetUint32E(key int) (uint32, error) {
+	pkg, err := this.getPkgE(key)
+	if err != nil {
+		return 0, err
 	}
+	ret, err := pkg.AsUint32()
+	return ret, keyError(err, key)
+}
+
+func (this *TLVObject) GetUint64(key int) (uint64, bool) {
+	ret, err := this.GetUint64E(key)
+	return ret, err == nil
+}
 
-	value := findObject.Pkg.Value
-	if len(value) != digit {
-		fmt.Printf("该字段不是int8类型, key:%v, value:%v\n", key, value)
-		return 0, false
+func (this *TLVObject) GetUint64E(key int) (uint64, error) {
+	pkg, err := this.getPkgE(key)
+	if err != nil {
+		return 0, err
 	}
+	ret, err := pkg.AsUint64()
+	return ret, keyError(err, key)
+}
 
-	switch digit {
-	case 2:
-		ret = int64(binary.BigEndian.Uint16(value))
-	case 4:
-		ret = int64(binary.BigEndian.Uint32(value))
-	case 8:
-		ret = int64(binary.BigEndian.Uint64(value))
-	default:
-		ok = false
-		fmt.Printf("int number of bytes invalid: %v\n", digit)
+func (this *TLVObject) GetVarUint(key int) (ret uint64, ok bool) {
+	ret, err := this.GetVarUintE(key)
+	return ret, err == nil
+}
+
+func (this *TLVObject) GetVarUintE(key int) (uint64, error) {
+	pkg, err := this.getPkgE(key)
+	if err != nil {
+		return 0, err
 	}
+	ret, err := pkg.AsVarUint()
+	return ret, keyError(err, key)
+}
 
-	return ret, ok
+func (this *TLVObject) GetVarInt(key int) (ret int64, ok bool) {
+	ret, err := this.GetVarIntE(key)
+	return ret, err == nil
 }
 
-func (this *TLVObject) GetInt16(key int) (int16, bool) {
-	ret, ok := this.getIntWithDigit(key, 2)
-	return int16(ret), ok
+func (this *TLVObject) GetVarIntE(key int) (int64, error) {
+	pkg, err := this.getPkgE(key)
+	if err != nil {
+		return 0, err
+	}
+	ret, err := pkg.AsVarInt()
+	return ret, keyError(err, key)
 }
 
-func (this *TLVObject) GetInt32(key int) (int32, bool) {
-	ret, ok := this.getIntWithDigit(key, 4)
-	return int32(ret), ok
+func (this *TLVObject) GetCompactUint(key int) (ret uint64, ok bool) {
+	ret, err := this.GetCompactUintE(key)
+	return ret, err == nil
 }
 
-func (this *TLVObject) GetInt64(key int) (int64, bool) {
-	ret, ok := this.getIntWithDigit(key, 8)
-	return ret, ok
+func (this *TLVObject) GetCompactUintE(key int) (uint64, error) {
+	pkg, err := this.getPkgE(key)
+	if err != nil {
+		return 0, err
+	}
+	ret, err := pkg.AsCompactUint()
+	return ret, keyError(err, key)
 }
 
-func (this *TLVObject) GetUint16(key int) (uint16, bool) {
-	ret, ok := this.getIntWithDigit(key, 2)
-	return uint16(ret), ok
+func (this *TLVObject) GetCompactInt(key int) (ret int64, ok bool) {
+	ret, err := this.GetCompactIntE(key)
+	return ret, err == nil
 }
 
-func (this *TLVObject) GetUint32(key int) (uint32, bool) {
-	ret, ok := this.getIntWithDigit(key, 4)
-	return uint32(ret), ok
+func (this *TLVObject) GetCompactIntE(key int) (int64, error) {
+	pkg, err := this.getPkgE(key)
+	if err != nil {
+		return 0, err
+	}
+	ret, err := pkg.AsCompactInt()
+	return ret, keyError(err, key)
 }
 
-func (this *TLVObject) GetUint64(key int) (uint64, bool) {
-	ret, ok := this.getIntWithDigit(key, 8)
-	return uint64(ret), ok
+func (this *TLVObject) GetBytes(key int) ([]byte, bool) {
+	ret, err := this.GetBytesE(key)
+	return ret, err == nil
 }
 
-func (this *TLVObject) GetVarUint(key int) (ret uint64, ok bool) {
-	findObject, ok := findTLVObject(this, key)
-	if ok == false {
-		//fmt.Printf("don't exist this field, key:%v\n", key)
-		return 0, false
+func (this *TLVObject) GetBytesE(key int) ([]byte, error) {
+	pkg, err := this.getPkgE(key)
+	if err != nil {
+		return nil, err
+	}
+	if pkg.IsNull() {
+		return nil, newKeyError(errNull, key)
 	}
+	return pkg.Value, nil
+}
 
-	value := findObject.Pkg.Value
-	digit := len(value)
+func (this *TLVObject) GetString(key int) (ret string, ok bool) {
+	ret, err := this.GetStringE(key)
+	return ret, err == nil
+}
 
-	switch digit {
-	case 1:
-		ret = uint64(value[0])
-	case 2:
-		ret = uint64(binary.BigEndian.Uint16(value))
-	case 4:
-		ret = uint64(binary.BigEndian.Uint32(value))
-	case 8:
-		ret = uint64(binary.BigEndian.Uint64(value))
-	default:
-		ok = false
-		fmt.Printf("int number of bytes invalid: %v\n", digit)
+func (this *TLVObject) GetStringE(key int) (string, error) {
+	pkg, err := this.getPkgE(key)
+	if err != nil {
+		return "", err
 	}
+	ret, err := pkg.AsString()
+	return ret, keyError(err, key)
+}
 
-	return ret, ok
+func (this *TLVObject) GetFloat32(key int) (ret float32, ok bool) {
+	ret, err := this.GetFloat32E(key)
+	return ret, err == nil
 }
 
-func (this *TLVObject) GetVarInt(key int) (ret int64, ok bool) {
-	findObject, ok := findTLVObject(this, key)
-	if ok == false {
-		//fmt.Printf("don't exist this field, key:%v\n", key)
-		return 0, false
+func (this *TLVObject) GetFloat32E(key int) (float32, error) {
+	pkg, err := this.getPkgE(key)
+	if err != nil {
+		return 0, err
 	}
+	ret, err := pkg.AsFloat32()
+	return ret, keyError(err, key)
+}
 
-	value := findObject.Pkg.Value
-	digit := len(value)
+func (this *TLVObject) GetFloat64(key int) (ret float64, ok bool) {
+	ret, err := this.GetFloat64E(key)
+	return ret, err == nil
+}
 
-	switch digit {
-	case 1:
-		ret = int64(value[0])
-	case 2:
-		ret = int64(binary.BigEndian.Uint16(value))
-	case 4:
-		ret = int64(binary.BigEndian.Uint32(value))
-	case 8:
-		ret = int64(binary.BigEndian.Uint64(value))
-	default:
-		ok = false
-		fmt.Printf("int number of bytes invalid: %v\n", digit)
+func (this *TLVObject) GetFloat64E(key int) (float64, error) {
+	pkg, err := this.getPkgE(key)
+	if err != nil {
+		return 0, err
 	}
+	ret, err := pkg.AsFloat64()
+	return ret, keyError(err, key)
+}
 
-	return ret, ok
+// 读取PutTime写入的时间，返回UTC时间
+func (this *TLVObject) GetTime(key int) (ret time.Time, ok bool) {
+	ret, err := this.GetTimeE(key)
+	return ret, err == nil
 }
 
-func (this *TLVObject) GetBytes(key int) ([]byte, bool) {
-	findObject, ok := findTLVObject(this, key)
-	if ok == false {
-		//fmt.Printf("don't exist this field, key:%v\n", key)
-		return nil, false
+func (this *TLVObject) GetTimeE(key int) (time.Time, error) {
+	pkg, err := this.getPkgE(key)
+	if err != nil {
+		return time.Time{}, err
 	}
+	ret, err := pkg.AsTime()
+	return ret, keyError(err, key)
+}
 
-	return findObject.Pkg.Value, true
+func (this *TLVObject) GetDuration(key int) (ret time.Duration, ok bool) {
+	ret, err := this.GetDurationE(key)
+	return ret, err == nil
 }
 
-func (this *TLVObject) GetString(key int) (ret string, ok bool) {
-	findObject, ok := findTLVObject(this, key)
-	if ok == false {
-		//fmt.Printf("don't exist this field, key:%v\n", key)
-		return "", false
+func (this *TLVObject) GetDurationE(key int) (time.Duration, error) {
+	pkg, err := this.getPkgE(key)
+	if err != nil {
+		return 0, err
 	}
-	ret = string(findObject.Pkg.Value)
-	return ret, true
+	ret, err := pkg.AsDuration()
+	return ret, keyError(err, key)
 }
 
+// 以不定长方式添加一个TLV嵌套结构，适用于编码前无法确定数据大小的场景
+func (this *TLVObject) PutIndefinite(key int, tlvObject *TLVObject) error {
+	if tlvObject == nil {
+		tlvObject = &TLVObject{}
+	}
+	if err := this.Put(key, tlvObject); err != nil {
+		return err
+	}
+	tlvObject.Pkg.Indefinite = true
+	return nil
+}
+
+// 添加一个TLV嵌套结构，tlvObject为nil时添加没有子节点的空结构
 func (this *TLVObject) Put(key int, tlvObject *TLVObject) error {
-	tlvObject.Pkg.FrameType = FarmeTypePrimitive
+	if tlvObject == nil {
+		tlvObject = &TLVObject{}
+	}
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	if this.hasAncestor(tlvObject) {
+		return fmt.Errorf("%w: 不能将节点自身或祖先节点添加为子节点", ErrInvalidParam)
+	}
+	tlvObject.Pkg.FrameType, tlvObject.Pkg.TagValue, _ = splitKey(key)
 	tlvObject.Pkg.DataType = DataTypeStruct
-	tlvObject.Pkg.TagValue = key
 	this.addNode(tlvObject)
 	return nil
 }
 
 // 添加基本数据节点
-func (this *TLVObject) addPrimitiveNode(key int, valueBytes []byte) {
+func (this *TLVObject) addPrimitiveNode(key int, valueBytes []byte) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	frameType, tagValue, _ := splitKey(key)
 	pkg := TLVPkg{
-		FrameType: FarmeTypePrimitive,
+		FrameType: frameType,
 		DataType:  DataTypePrimitive,
-		TagValue:  key,
+		TagValue:  tagValue,
 		Value:     valueBytes,
 	}
 	pkg.Build()
@@ -344,6 +651,7 @@ func (this *TLVObject) addPrimitiveNode(key int, valueBytes []byte) {
 		Pkg: pkg,
 	}
 	this.addNode(&newNode)
+	return nil
 }
 
 func (this *TLVObject) PutBool(key int, value bool) error {
@@ -352,8 +660,7 @@ func (this *TLVObject) PutBool(key int, value bool) error {
 		valueBytes[0] = 1
 	}
 
-	this.addPrimitiveNode(key, valueBytes)
-	return nil
+	return this.addPrimitiveNode(key, valueBytes)
 }
 
 func (this *TLVObject) PutInt8(key int, value int8) error {
@@ -363,8 +670,7 @@ func (this *TLVObject) PutInt8(key int, value int8) error {
 func (this *TLVObject) PutUint8(key int, value uint8) error {
 	valueBytes := []byte{byte(value)}
 
-	this.addPrimitiveNode(key, valueBytes)
-	return nil
+	return this.addPrimitiveNode(key, valueBytes)
 }
 
 func (this *TLVObject) PutInt16(key int, value int16) error {
@@ -375,8 +681,7 @@ func (this *TLVObject) PutUint16(key int, value uint16) error {
 	valueBytes := make([]byte, 2)
 	binary.BigEndian.PutUint16(valueBytes, value)
 
-	this.addPrimitiveNode(key, valueBytes)
-	return nil
+	return this.addPrimitiveNode(key, valueBytes)
 }
 
 func (this *TLVObject) PutInt32(key int, value int32) error {
@@ -387,8 +692,7 @@ func (this *TLVObject) PutUint32(key int, value uint32) error {
 	valueBytes := make([]byte, 4)
 	binary.BigEndian.PutUint32(valueBytes, value)
 
-	this.addPrimitiveNode(key, valueBytes)
-	return nil
+	return this.addPrimitiveNode(key, valueBytes)
 }
 
 func (this *TLVObject) PutInt64(key int, value int64) error {
@@ -396,7 +700,7 @@ func (this *TLVObject) PutInt64(key int, value int64) error {
 }
 
 // 写入任意长度的整形数据
-// 范围为int8到int64之间，根据数值大小，自动计算
+// 范围为int8到int64之间，根据数值大小，自动计算，需要使用GetVarInt按符号位扩展读取
 func (this *TLVObject) PutVarInt(key int, value int64) (err error) {
 	if value >= math.MinInt8 && value <= math.MaxInt8 {
 		err = this.PutInt8(key, int8(value))
@@ -411,6 +715,7 @@ func (this *TLVObject) PutVarInt(key int, value int64) (err error) {
 	return err
 }
 
+// 按数值大小使用1/2/4/8字节写入无符号整数，需要使用GetVarUint读取
 func (this *TLVObject) PutVarUint(key int, value uint64) (err error) {
 	if value >= 0 && value <= math.MaxUint8 {
 		err = this.PutUint8(key, uint8(value))
@@ -425,49 +730,135 @@ func (this *TLVObject) PutVarUint(key int, value uint64) (err error) {
 	return err
 }
 
+// 按LEB128方式写入无符号整数，每字节保存7位，数值越小占用的字节越少，最多10字节
+func (this *TLVObject) PutCompactUint(key int, value uint64) error {
+	return this.addPrimitiveNode(key, binary.AppendUvarint(nil, value))
+}
+
+// 先按ZigZag方式将符号位移到最低位，再按LEB128方式写入整数，绝对值较小的负数同样只占用少量字节
+// 与PutVarInt不同，读取时不需要知道写入时的位数，任意int64都能原样读回
+func (this *TLVObject) PutCompactInt(key int, value int64) error {
+	return this.addPrimitiveNode(key, binary.AppendVarint(nil, value))
+}
+
 func (this *TLVObject) PutUint64(key int, value uint64) error {
 	valueBytes := make([]byte, 8)
 	binary.BigEndian.PutUint64(valueBytes, value)
 
-	this.addPrimitiveNode(key, valueBytes)
-	return nil
+	return this.addPrimitiveNode(key, valueBytes)
 }
 
 func (this *TLVObject) PutBytes(key int, value []byte) error {
-	this.addPrimitiveNode(key, value)
-	return nil
+	return this.addPrimitiveNode(key, value)
 }
 
-func (this *TLVObject) PutString(key int, value string) error {
-	if value == "" {
-		return ErrInvalidParam
+// 添加TLV嵌套结构，tlvObject为nil时不添加
+func (this *TLVObject) putObject(key int, tlvObject *TLVObject) error {
+	if tlvObject == nil {
+		return nil
 	}
+	return this.Put(key, tlvObject)
+}
 
+// 添加字符串节点，空字符串编码为长度为0的数据段，与null及字段不存在都不同
+func (this *TLVObject) PutString(key int, value string) error {
 	valueBytes := []byte(value)
-	this.addPrimitiveNode(key, valueBytes)
+	return this.addPrimitiveNode(key, valueBytes)
+}
+
+// 添加null节点，表示字段存在但值为空，可以与字段不存在及空值区分
+// null编码为以不定长方式表示长度、数据段为空的基本数据，即tag、不定长标记及结束标记
+// 基本数据使用不定长方式是对X.690的扩展，严格按X.690实现的解码器不接受null
+func (this *TLVObject) PutNull(key int) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	frameType, tagValue, _ := splitKey(key)
+	pkg := TLVPkg{
+		FrameType:  frameType,
+		DataType:   DataTypePrimitive,
+		TagValue:   tagValue,
+		Indefinite: true,
+	}
+	pkg.Build()
+
+	this.addNode(&TLVObject{Pkg: pkg})
 	return nil
 }
 
+// 按IEEE-754大端格式写入4字节浮点数
+func (this *TLVObject) PutFloat32(key int, value float32) error {
+	return this.PutUint32(key, math.Float32bits(value))
+}
+
+// 按IEEE-754大端格式写入8字节浮点数
+func (this *TLVObject) PutFloat64(key int, value float64) error {
+	return this.PutUint64(key, math.Float64bits(value))
+}
+
+// PutTime可以表示的时间范围，即int64纳秒数的范围
+var (
+	minTime = time.Unix(0, math.MinInt64)
+	maxTime = time.Unix(0, math.MaxInt64)
+)
+
+// 写入时间，编码为距Unix纪元(1970-01-01 00:00:00 UTC)的纳秒数，8字节有符号整数
+// 可以表示1677年至2262年之间的时间，超出范围时返回ErrInvalidParam，时区信息不保留
+// 零值(IsZero为true)编码为空数据段，读取时还原为time.Time{}
+func (this *TLVObject) PutTime(key int, value time.Time) error {
+	if value.IsZero() {
+		return this.addPrimitiveNode(key, nil)
+	}
+	if value.Before(minTime) || value.After(maxTime) {
+		return fmt.Errorf("%w: 时间%v超出范围", ErrInvalidParam, value)
+	}
+	return this.PutInt64(key, value.UnixNano())
+}
+
+// 写入时间间隔，编码为纳秒数，8字节有符号整数
+func (this *TLVObject) PutDuration(key int, value time.Duration) error {
+	return this.PutInt64(key, int64(value))
+}
+
 // 构建TLV嵌套结构的节点数据
-func buildNode(node []*TLVObject) (nodeBytes []byte) {
+func buildNode(node []*TLVObject, codec Codec) (nodeBytes []byte, err error) {
 	//fmt.Printf("node count:%v\n", len(node))
+	if codec.Canonical {
+		node = canonicalNode(node)
+	}
+
 	for i := 0; i < len(node); i++ {
+		if node[i].rawValid(codec) {
+			nodeBytes = append(nodeBytes, node[i].raw...)
+			continue
+		}
 		if node[i].Pkg.DataType == DataTypeStruct {
-			node[i].Pkg.Value = buildNode(node[i].node)
-			node[i].Pkg.Build()
+			node[i].Pkg.definite = hasEndOfContents(node[i].node)
+			node[i].Pkg.Value, err = node[i].encodeValue(codec)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if err = node[i].Pkg.BuildWith(codec); err != nil {
+			return nil, err
 		}
 		//fmt.Printf("append pkg:%v", node[i].Pkg)
-		nodeBytes = append(nodeBytes, node[i].Pkg.Bytes()...)
+		nodeBytes = append(nodeBytes, node[i].Pkg.data...)
 		//fmt.Printf("nodeBytes:%v\n\n", nodeBytes)
 	}
 
-	return nodeBytes
+	return nodeBytes, nil
 }
 
 // 获取TLV的字节数据
+// 结果在子节点修改前会被缓存，修改子节点后只重新编码发生变化的子树，返回的切片不应修改
+// 编码失败时返回nil而不是不完整的数据，例如legacy规则不支持的帧类型，需要错误信息时使用BytesWith
 func (this *TLVObject) Bytes() []byte {
-	if this.Pkg.Value == nil {
-		this.Pkg.Value = buildNode(this.node)
-	}
-	return this.Pkg.Value
+	ret, _ := this.encodeValue(Codec{})
+	return ret
+}
+
+// 按指定的编码规则获取TLV的字节数据，缓存规则与Bytes相同
+func (this *TLVObject) BytesWith(codec Codec) ([]byte, error) {
+	return this.encodeValue(codec)
 }
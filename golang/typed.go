@@ -0,0 +1,229 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// 实现自描述的数据：基本数据的数据段以类型码开头，不需要预先约定字段类型即可解码为Go的值
+//
+// 编码中没有标记表明数据是自描述的，解码时只能假定数据由PutValue或FromGo写入。
+// 对PutInt32等方法写入的普通数据使用AsValue、GetValue、Interface或ToGo时，
+// 首字节恰好是有效类型码的数据会被解码为错误的值且不返回错误，例如PutInt32(1, 0x04000041)读取为字符串"\x00\x00A"
+package golang
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// 自描述数据的类型码，位于基本数据数据段的第一个字节
+// 0x00不是有效的类型码，nil编码为null节点
+type TypeCode byte
+
+const (
+	TypeBool   TypeCode = 0x01 // 1字节bool
+	TypeInt    TypeCode = 0x02 // ZigZag编码的整数，与PutCompactInt相同
+	TypeFloat  TypeCode = 0x03 // 8字节IEEE-754浮点数
+	TypeString TypeCode = 0x04 // 字符串
+	TypeBytes  TypeCode = 0x05 // 字节数组
+)
+
+// 将Go的值编码为带类型码的数据段
+func encodeTyped(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return []byte{byte(TypeBool), 1}, nil
+		}
+		return []byte{byte(TypeBool), 0}, nil
+	case int:
+		return binary.AppendVarint([]byte{byte(TypeInt)}, int64(v)), nil
+	case int8:
+		return binary.AppendVarint([]byte{byte(TypeInt)}, int64(v)), nil
+	case int16:
+		return binary.AppendVarint([]byte{byte(TypeInt)}, int64(v)), nil
+	case int32:
+		return binary.AppendVarint([]byte{byte(TypeInt)}, int64(v)), nil
+	case int64:
+		return binary.AppendVarint([]byte{byte(TypeInt)}, v), nil
+	case uint8:
+		return binary.AppendVarint([]byte{byte(TypeInt)}, int64(v)), nil
+	case uint16:
+		return binary.AppendVarint([]byte{byte(TypeInt)}, int64(v)), nil
+	case uint32:
+		return binary.AppendVarint([]byte{byte(TypeInt)}, int64(v)), nil
+	case uint, uint64:
+		u := uint64(0)
+		if x, ok := v.(uint); ok {
+			u = uint64(x)
+		} else {
+			u = v.(uint64)
+		}
+		if u > math.MaxInt64 {
+			return nil, fmt.Errorf("%w: %d超出int64的范围", ErrInvalidParam, u)
+		}
+		return binary.AppendVarint([]byte{byte(TypeInt)}, int64(u)), nil
+	case float32:
+		return binary.BigEndian.AppendUint64([]byte{byte(TypeFloat)}, math.Float64bits(float64(v))), nil
+	case float64:
+		return binary.BigEndian.AppendUint64([]byte{byte(TypeFloat)}, math.Float64bits(v)), nil
+	case string:
+		return append([]byte{byte(TypeString)}, v...), nil
+	case []byte:
+		return append([]byte{byte(TypeBytes)}, v...), nil
+	}
+	return nil, fmt.Errorf("%w: %T", ErrUnsupportedType, value)
+}
+
+// 将带类型码的数据段解码为Go的值，只适用于PutValue写入的数据
+// 返回bool、int64、float64、string或[]byte
+func (this *TLVPkg) AsValue() (interface{}, error) {
+	if this.DataType == DataTypeStruct || len(this.Value) == 0 {
+		return nil, fmt.Errorf("%w: 不是自描述数据", ErrTypeMismatch)
+	}
+
+	code, value := TypeCode(this.Value[0]), this.Value[1:]
+	switch code {
+	case TypeBool:
+		if len(value) == 1 {
+			return value[0]&0x01 > 0, nil
+		}
+	case TypeInt:
+		ret, n := binary.Varint(value)
+		if n > 0 && n == len(value) {
+			return ret, nil
+		}
+	case TypeFloat:
+		if len(value) == 8 {
+			return math.Float64frombits(binary.BigEndian.Uint64(value)), nil
+		}
+	case TypeString:
+		return string(value), nil
+	case TypeBytes:
+		return append([]byte{}, value...), nil
+	}
+	return nil, fmt.Errorf("%w: 类型码0x%02x的数据无效", ErrTypeMismatch, byte(code))
+}
+
+// 按Go的值添加自描述节点
+//
+// nil添加null节点，与PutNull相同；
+// bool、整数、浮点数、string及[]byte编码为带类型码的基本数据，整数统一按int64、浮点数按float64编码；
+// map[int]interface{}编码为TLV嵌套结构，键为子节点的key，可以使用ClassTag；
+// []interface{}编码为多个tag相同的节点，只有一个元素时读取结果为单个值，没有元素时不添加节点
+func (this *TLVObject) PutValue(key int, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		return this.PutNull(key)
+	case map[int]interface{}:
+		child := &TLVObject{}
+		if err := child.putValues(v); err != nil {
+			return err
+		}
+		return this.Put(key, child)
+	case []interface{}:
+		for _, item := range v {
+			if err := this.PutValue(key, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	valueBytes, err := encodeTyped(value)
+	if err != nil {
+		return newKeyError(err, key)
+	}
+	return this.addPrimitiveNode(key, valueBytes)
+}
+
+// 按key从小到大添加map中的所有值，使编码结果确定
+func (this *TLVObject) putValues(m map[int]interface{}) error {
+	keys := make([]int, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+
+	for _, key := range keys {
+		if err := this.PutValue(key, m[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 按map[int]interface{}创建TLVObject，是ToGo的逆操作
+func FromGo(m map[int]interface{}) (*TLVObject, error) {
+	tlvObject := &TLVObject{}
+	if err := tlvObject.putValues(m); err != nil {
+		return nil, err
+	}
+	return tlvObject, nil
+}
+
+// 读取PutValue写入的自描述节点，不能用于其他方式写入的节点
+func (this *TLVObject) GetValue(key int) (interface{}, bool) {
+	ret, err := this.GetValueE(key)
+	return ret, err == nil
+}
+
+func (this *TLVObject) GetValueE(key int) (interface{}, error) {
+	findObject, err := this.GetE(key)
+	if err != nil {
+		return nil, err
+	}
+	ret, err := findObject.Interface()
+	if err != nil {
+		return nil, prependPath(err, key)
+	}
+	return ret, nil
+}
+
+// 将节点解码为Go的值，TLV嵌套结构解码为ToGo的结果，null解码为nil，基本数据按类型码解码
+// 只适用于PutValue或FromGo写入的数据，见本文件开头的说明
+func (this *TLVObject) Interface() (interface{}, error) {
+	if this.Pkg.IsNull() {
+		return nil, nil
+	}
+	if this.Pkg.DataType == DataTypeStruct || len(this.node) > 0 {
+		return this.ToGo()
+	}
+	return this.Pkg.AsValue()
+}
+
+// 将所有子节点解码为map[int]interface{}，键为子节点的tag，帧类型不是universal时为ClassTag生成的key
+// tag相同的多个子节点解码为[]interface{}
+// 只适用于PutValue或FromGo写入的数据，普通数据可能被解码为错误的值而不返回错误
+func (this *TLVObject) ToGo() (map[int]interface{}, error) {
+	ret := make(map[int]interface{}, len(this.node))
+	for _, node := range this.node {
+		key := pkgKey(&node.Pkg)
+		value, err := node.Interface()
+		if err != nil {
+			return nil, prependPath(err, key)
+		}
+
+		if old, ok := ret[key]; ok {
+			// Interface不会返回[]interface{}，因此old为[]interface{}时一定是之前合并的结果
+			if list, ok := old.([]interface{}); ok {
+				ret[key] = append(list, value)
+			} else {
+				ret[key] = []interface{}{old, value}
+			}
+			continue
+		}
+		ret[key] = value
+	}
+	return ret, nil
+}
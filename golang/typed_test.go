@@ -0,0 +1,113 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golang
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTypedValue(t *testing.T) {
+	value := map[int]interface{}{
+		1:                             nil,
+		2:                             true,
+		3:                             int64(-300),
+		4:                             1.5,
+		5:                             "hello",
+		6:                             []byte{0x01, 0x02},
+		7:                             map[int]interface{}{0: "nested", 1: int64(1 << 40)},
+		8:                             []interface{}{int64(1), "two", map[int]interface{}{}},
+		ClassTag(ClassApplication, 9): false,
+	}
+
+	tlvBuilder, err := FromGo(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tlvParser := TLVObject{}
+	if err = tlvParser.FromBytes(tlvBuilder.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	ret, err := tlvParser.ToGo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.DeepEqual(ret, value) == false {
+		t.Errorf("ret = %#v", ret)
+	}
+
+	//其他整数及浮点数类型统一读取为int64及float64
+	tlvBuilder = &TLVObject{}
+	tlvBuilder.PutValue(1, uint16(7))
+	tlvBuilder.PutValue(2, float32(0.25))
+	if v, ok := tlvBuilder.GetValue(1); ok == false || v != int64(7) {
+		t.Errorf("v = %#v", v)
+	}
+	if v, _ := tlvBuilder.GetValue(2); v != float64(0.25) {
+		t.Errorf("v = %#v", v)
+	}
+
+	if err = tlvBuilder.PutValue(3, uint64(1<<63)); errors.Is(err, ErrInvalidParam) == false {
+		t.Errorf("err = %v", err)
+	}
+	if err = tlvBuilder.PutValue(3, struct{}{}); errors.Is(err, ErrUnsupportedType) == false {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestTypedValueError(t *testing.T) {
+	tlvObject := TLVObject{}
+	tlvObject.PutInt32(1, 5)
+	child := &TLVObject{}
+	child.PutBytes(2, []byte{byte(TypeInt), 0x80})
+	tlvObject.Put(3, child)
+
+	//普通节点的首字节不是有效的类型码
+	if _, err := tlvObject.GetValueE(1); errors.Is(err, ErrTypeMismatch) == false {
+		t.Errorf("err = %v", err)
+	}
+	_, err := tlvObject.GetValueE(3)
+	if errors.Is(err, ErrTypeMismatch) == false || formatPath(err.(*Error).Path) != "3/2" {
+		t.Errorf("err = %v", err)
+	}
+	if _, err = tlvObject.GetValueE(4); errors.Is(err, ErrNotFound) == false {
+		t.Errorf("err = %v", err)
+	}
+}
+
+// 自描述数据没有标记，只能用于PutValue写入的数据
+func TestTypedValueNull(t *testing.T) {
+	tlvObject := TLVObject{}
+	tlvObject.PutValue(1, nil)
+	tlvObject.PutUint8(2, 0)
+	tlvObject.PutInt32(3, 0x04000041)
+
+	if tlvObject.IsNull(1) == false {
+		t.Errorf("PutValue(nil)应添加null节点")
+	}
+	if v, err := tlvObject.GetValueE(1); v != nil || err != nil {
+		t.Errorf("v = %v, err = %v", v, err)
+	}
+	//0x00不是有效的类型码
+	if _, err := tlvObject.GetValueE(2); errors.Is(err, ErrTypeMismatch) == false {
+		t.Errorf("err = %v", err)
+	}
+	//首字节恰好是有效类型码的普通数据无法识别
+	if v, err := tlvObject.GetValueE(3); v != "\x00\x00A" || err != nil {
+		t.Errorf("v = %#v, err = %v", v, err)
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// 保留解码时未识别的字段，使旧版本的服务转发新版本的消息时不丢失新增字段
+package golang
+
+// 解码时未识别的子节点，编码时按解码得到的原始字节输出
+//
+// 作为结构体字段时由Unmarshal填充，Marshal时追加在其他字段之后，不需要tlv标签。
+// 编码规则与解码时相同时输出原始字节，否则按节点的数据重新编码
+type UnknownFields []*TLVObject
+
+// 保存一个未识别的子节点
+// 解码得到且未修改过的节点保留原始字节，其他节点(如通过Put*添加的节点)编码时按节点的数据编码；
+// 保存后通过Set*等方法修改子节点，或直接修改Pkg后调用MarkDirty，原始字节即失效
+func (this *UnknownFields) Add(node *TLVObject) {
+	saved := &TLVObject{
+		Pkg:  node.Pkg,
+		node: append([]*TLVObject(nil), node.node...),
+	}
+	//子节点改为属于saved，修改子节点时清除saved的原始字节
+	saved.adoptChildren()
+	if node.source != nil && node.Pkg.data != nil && node.Pkg.stale() == false &&
+		node.Pkg.built.profile == node.source.Profile && (node.Pkg.DataType != DataTypeStruct || node.cache != nil) {
+		saved.raw = node.Pkg.data
+		saved.rawCodec = *node.source
+	}
+	*this = append(*this, saved)
+}
+
+// 将保存的子节点添加到tlvObject下
+func (this UnknownFields) PutTo(tlvObject *TLVObject) {
+	for _, node := range this {
+		tlvObject.addNode(node)
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golang
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// 检查数据段的长度，digit为0时不检查
+func (this *TLVPkg) valueWithDigit(digit int) ([]byte, error) {
+	if digit > 0 && len(this.Value) != digit {
+		return nil, fmt.Errorf("%w: 需要%d字节, 实际为%d字节", ErrTypeMismatch, digit, len(this.Value))
+	}
+	return this.Value, nil
+}
+
+// 将数据段解码为bool
+func (this *TLVPkg) AsBool() (bool, error) {
+	value, err := this.valueWithDigit(1)
+	if err != nil {
+		return false, err
+	}
+	return value[0]&0x01 > 0, nil
+}
+
+func (this *TLVPkg) AsInt8() (int8, error) {
+	ret, err := this.AsUint8()
+	return int8(ret), err
+}
+
+func (this *TLVPkg) AsUint8() (uint8, error) {
+	value, err := this.valueWithDigit(1)
+	if err != nil {
+		return 0, err
+	}
+	return value[0], nil
+}
+
+func (this *TLVPkg) AsInt16() (int16, error) {
+	ret, err := this.AsUint16()
+	return int16(ret), err
+}
+
+func (this *TLVPkg) AsUint16() (uint16, error) {
+	value, err := this.valueWithDigit(2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint16(value), nil
+}
+
+func (this *TLVPkg) AsInt32() (int32, error) {
+	ret, err := this.AsUint32()
+	return int32(ret), err
+}
+
+func (this *TLVPkg) AsUint32() (uint32, error) {
+	value, err := this.valueWithDigit(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(value), nil
+}
+
+func (this *TLVPkg) AsInt64() (int64, error) {
+	ret, err := this.AsUint64()
+	return int64(ret), err
+}
+
+func (this *TLVPkg) AsUint64() (uint64, error) {
+	value, err := this.valueWithDigit(8)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(value), nil
+}
+
+// 将PutVarUint写入的1/2/4/8字节数据解码为无符号整数
+func (this *TLVPkg) AsVarUint() (uint64, error) {
+	switch len(this.Value) {
+	case 1, 2, 4, 8:
+		return decodeUint(this.Value), nil
+	}
+	return 0, fmt.Errorf("%w: 整数不能为%d字节", ErrTypeMismatch, len(this.Value))
+}
+
+// 将PutVarInt写入的1/2/4/8字节数据按符号位扩展解码为整数
+func (this *TLVPkg) AsVarInt() (int64, error) {
+	if _, err := this.AsVarUint(); err != nil {
+		return 0, err
+	}
+	return signExtend(this.Value), nil
+}
+
+// 将PutCompactUint写入的LEB128数据解码为无符号整数
+func (this *TLVPkg) AsCompactUint() (uint64, error) {
+	ret, n := binary.Uvarint(this.Value)
+	if n <= 0 || n != len(this.Value) {
+		return 0, fmt.Errorf("%w: 无效的LEB128数据", ErrTypeMismatch)
+	}
+	return ret, nil
+}
+
+// 将PutCompactInt写入的ZigZag编码数据解码为整数
+func (this *TLVPkg) AsCompactInt() (int64, error) {
+	ret, n := binary.Varint(this.Value)
+	if n <= 0 || n != len(this.Value) {
+		return 0, fmt.Errorf("%w: 无效的ZigZag数据", ErrTypeMismatch)
+	}
+	return ret, nil
+}
+
+func (this *TLVPkg) AsString() (string, error) {
+	if this.IsNull() {
+		return "", errNull
+	}
+	return string(this.Value), nil
+}
+
+// 将4字节IEEE-754大端数据解码为float32
+func (this *TLVPkg) AsFloat32() (float32, error) {
+	ret, err := this.AsUint32()
+	return math.Float32frombits(ret), err
+}
+
+// 将8字节IEEE-754大端数据解码为float64
+func (this *TLVPkg) AsFloat64() (float64, error) {
+	ret, err := this.AsUint64()
+	return math.Float64frombits(ret), err
+}
+
+// 将距Unix纪元的纳秒数解码为UTC时间，空数据段解码为零值time.Time{}
+func (this *TLVPkg) AsTime() (time.Time, error) {
+	if len(this.Value) == 0 && this.IsNull() == false {
+		return time.Time{}, nil
+	}
+	ret, err := this.AsInt64()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, ret).UTC(), nil
+}
+
+// 将纳秒数解码为time.Duration
+func (this *TLVPkg) AsDuration() (time.Duration, error) {
+	ret, err := this.AsInt64()
+	return time.Duration(ret), err
+}
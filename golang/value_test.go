@@ -0,0 +1,158 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golang
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestFloat(t *testing.T) {
+	tlvBuilder := TLVObject{}
+	tlvBuilder.PutFloat32(1, 1.5)
+	tlvBuilder.PutFloat64(2, -math.Pi)
+	tlvBuilder.PutFloat64(3, math.Inf(1))
+
+	//与binary.BigEndian整数的字节序一致
+	want := []byte{0x01, 0x04, 0x3f, 0xc0, 0x00, 0x00}
+	if bytes.Equal(tlvBuilder.Bytes()[:6], want) == false {
+		t.Errorf("%x", tlvBuilder.Bytes())
+	}
+
+	tlvParser := TLVObject{}
+	if err := tlvParser.FromBytes(tlvBuilder.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if value, ok := tlvParser.GetFloat32(1); ok == false || value != 1.5 {
+		t.Errorf("value = %v", value)
+	}
+	if value, ok := tlvParser.GetFloat64(2); ok == false || value != -math.Pi {
+		t.Errorf("value = %v", value)
+	}
+	if value, ok := tlvParser.GetFloat64(3); ok == false || math.IsInf(value, 1) == false {
+		t.Errorf("value = %v", value)
+	}
+	if _, err := tlvParser.GetFloat64E(1); errors.Is(err, ErrTypeMismatch) == false {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestTimeDuration(t *testing.T) {
+	local := time.FixedZone("UTC+8", 8*3600)
+	when := time.Date(2024, 5, 6, 7, 8, 9, 123456789, local)
+
+	tlvBuilder := TLVObject{}
+	if err := tlvBuilder.PutTime(1, when); err != nil {
+		t.Fatal(err)
+	}
+	tlvBuilder.PutDuration(2, -1500*time.Millisecond)
+	if err := tlvBuilder.PutTime(4, time.Date(1600, 1, 1, 0, 0, 0, 0, time.UTC)); errors.Is(err, ErrInvalidParam) == false {
+		t.Errorf("err = %v", err)
+	}
+	//零值编码为空数据段
+	if err := tlvBuilder.PutTime(3, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	//epoch为Unix纪元，精度为纳秒
+	if value, ok := tlvBuilder.GetInt64(1); ok == false || value != when.UnixNano() {
+		t.Errorf("value = %d", value)
+	}
+
+	tlvParser := TLVObject{}
+	if err := tlvParser.FromBytes(tlvBuilder.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	value, ok := tlvParser.GetTime(1)
+	if ok == false || value.Equal(when) == false || value.Location() != time.UTC {
+		t.Errorf("value = %v", value)
+	}
+	if zero, ok := tlvParser.GetTime(3); ok == false || zero != (time.Time{}) {
+		t.Errorf("zero = %v", zero)
+	}
+	if duration, ok := tlvParser.GetDuration(2); ok == false || duration != -1500*time.Millisecond {
+		t.Errorf("duration = %v", duration)
+	}
+}
+
+// 测试PutVarInt写入的负数按符号位扩展读回
+func TestVarIntSign(t *testing.T) {
+	tlvObject := TLVObject{}
+	tlvObject.PutVarInt(1, -300)
+	tlvObject.PutVarInt(2, -1)
+	tlvObject.PutVarUint(3, 200)
+	tlvObject.PutVarInt(4, math.MinInt64)
+
+	if value, ok := tlvObject.GetVarInt(1); ok == false || value != -300 {
+		t.Errorf("value = %d", value)
+	}
+	if value, ok := tlvObject.GetVarInt(2); ok == false || value != -1 {
+		t.Errorf("value = %d", value)
+	}
+	if value, ok := tlvObject.GetVarUint(3); ok == false || value != 200 {
+		t.Errorf("value = %d", value)
+	}
+	if value, ok := tlvObject.GetVarInt(4); ok == false || value != math.MinInt64 {
+		t.Errorf("value = %d", value)
+	}
+}
+
+func TestCompactInt(t *testing.T) {
+	ints := []int64{0, 1, -1, 63, -64, 64, -300, math.MaxInt64, math.MinInt64}
+	uints := []uint64{0, 127, 128, 300, math.MaxUint32, math.MaxUint64}
+
+	tlvBuilder := TLVObject{}
+	for i, value := range ints {
+		tlvBuilder.PutCompactInt(i, value)
+	}
+	for i, value := range uints {
+		tlvBuilder.PutCompactUint(100+i, value)
+	}
+
+	tlvParser := TLVObject{}
+	if err := tlvParser.FromBytes(tlvBuilder.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range ints {
+		if value, err := tlvParser.GetCompactIntE(i); err != nil || value != want {
+			t.Errorf("value = %d, want = %d, err = %v", value, want, err)
+		}
+	}
+	for i, want := range uints {
+		if value, err := tlvParser.GetCompactUintE(100 + i); err != nil || value != want {
+			t.Errorf("value = %d, want = %d, err = %v", value, want, err)
+		}
+	}
+
+	//ZigZag编码：-1为0x01，-64为0x7f，64需要2字节
+	if value, _ := tlvParser.GetBytes(2); bytes.Equal(value, []byte{0x01}) == false {
+		t.Errorf("value = %x", value)
+	}
+	if value, _ := tlvParser.GetBytes(4); bytes.Equal(value, []byte{0x7f}) == false {
+		t.Errorf("value = %x", value)
+	}
+	if value, _ := tlvParser.GetBytes(5); len(value) != 2 {
+		t.Errorf("value = %x", value)
+	}
+
+	tlvObject := TLVObject{}
+	tlvObject.PutBytes(1, []byte{0x80})
+	if _, err := tlvObject.GetCompactIntE(1); errors.Is(err, ErrTypeMismatch) == false {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golang
+
+// 只读的TLV视图，直接在原始字节数据上按需解析，不复制数据也不分配内存
+// 适用于只需要读取大数据包中少数几个字段的场景
+type View struct {
+	raw       []byte // 整个TLV包的数据
+	value     []byte // 数据段，不定长方式时不含结束标记
+	frameType Class
+	dataType  byte
+	tagValue  int
+	codec     Codec
+}
+
+// 在字节数据上创建视图，只解析第一个TLV包
+func NewView(tlvBytes []byte) (View, error) {
+	return NewViewWith(tlvBytes, Codec{})
+}
+
+// 按指定的编码规则在字节数据上创建视图，只解析第一个TLV包
+func NewViewWith(tlvBytes []byte, codec Codec) (View, error) {
+	view, err := parseView(tlvBytes, codec)
+	if err != nil {
+		return View{}, newError(err, 0, nil)
+	}
+	return view, nil
+}
+
+// 解析TLV包头，得到视图
+func parseView(tlvBytes []byte, codec Codec) (View, error) {
+	pkg := TLVPkg{}
+	ok, err := codec.parseHead(tlvBytes, &pkg)
+	if err != nil {
+		return View{}, err
+	}
+	if ok == false {
+		return View{}, ErrTruncated
+	}
+
+	return View{
+		raw:       tlvBytes[:pkg.Size()],
+		value:     pkg.Value,
+		frameType: pkg.FrameType,
+		dataType:  pkg.DataType,
+		tagValue:  pkg.TagValue,
+		codec:     codec,
+	}, nil
+}
+
+// tag值
+func (this View) Tag() int {
+	return this.tagValue
+}
+
+// 帧类型
+func (this View) Class() Class {
+	return this.frameType
+}
+
+// 是否为TLV嵌套结构
+func (this View) IsStruct() bool {
+	return this.dataType == DataTypeStruct
+}
+
+// 数据段，与原始字节数据共享内存，不应修改
+func (this View) Value() []byte {
+	return this.value
+}
+
+// 整个TLV包的字节数据，与原始字节数据共享内存，不应修改
+func (this View) Bytes() []byte {
+	return this.raw
+}
+
+// 整个TLV包占用的字节数
+func (this View) Size() int {
+	return len(this.raw)
+}
+
+// 遍历嵌套结构的子节点，基本数据类型没有子节点
+func (this View) Children() ViewIterator {
+	iterator := ViewIterator{codec: this.codec}
+	if this.IsStruct() {
+		iterator.remain = this.value
+	}
+	return iterator
+}
+
+// 查找第一个与key匹配的子节点，key可以由ClassTag生成
+// 子节点数据有误时返回false，可以通过Children遍历得到具体的错误
+func (this View) Lookup(key int) (View, bool) {
+	iterator := this.Children()
+	for {
+		child, ok := iterator.Next()
+		if ok == false {
+			return View{}, false
+		}
+		if matchKey(child.frameType, child.tagValue, key) {
+			return child, true
+		}
+	}
+}
+
+// 子节点迭代器
+type ViewIterator struct {
+	remain []byte // 尚未遍历的数据
+	offset int    // 已经遍历的字节数
+	codec  Codec
+	err    error
+}
+
+// 返回下一个子节点，遍历结束或数据有误时返回false
+func (this *ViewIterator) Next() (View, bool) {
+	if this.err != nil || len(this.remain) == 0 {
+		return View{}, false
+	}
+
+	child, err := parseView(this.remain, this.codec)
+	if err != nil {
+		this.err = newError(err, this.offset, nil)
+		return View{}, false
+	}
+
+	this.remain = this.remain[child.Size():]
+	this.offset += child.Size()
+	return child, true
+}
+
+// 遍历过程中发生的错误，偏移相对于父节点的数据段
+func (this *ViewIterator) Err() error {
+	return this.err
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2014 Dataence, LLC. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golang
+
+import (
+	"errors"
+	"testing"
+)
+
+// 构建一个较宽的测试数据
+func buildWideObject(fieldCount int) *TLVObject {
+	tlvBuilder := &TLVObject{}
+	tlvObject := TLVObject{}
+	tlvBuilder.Put(1, &tlvObject)
+	for i := 0; i < fieldCount; i++ {
+		tlvObject.PutInt32(i, int32(i))
+	}
+	inner := TLVObject{}
+	tlvObject.PutIndefinite(ClassTag(ClassApplication, fieldCount), &inner)
+	inner.PutString(0, "zhoujunhua")
+	return tlvBuilder
+}
+
+// 测试视图读取的数据与TLVObject一致
+func TestView(t *testing.T) {
+	for _, codec := range []Codec{{}, {Profile: ProfileBER}} {
+		tlvBytes, _ := buildWideObject(100).BytesWith(codec)
+
+		view, err := NewViewWith(tlvBytes, codec)
+		if err != nil {
+			t.Fatalf("err = %v", err)
+		}
+		if view.Tag() != 1 || view.IsStruct() == false || view.Size() != len(tlvBytes) {
+			t.Fatalf("view = %v", view)
+		}
+
+		field, ok := view.Lookup(99)
+		if ok == false || field.IsStruct() || string(field.Value()) != "\x00\x00\x00\x63" {
+			t.Errorf("field = %v", field)
+		}
+
+		if _, ok := view.Lookup(ClassTag(ClassUniversal, 100)); ok {
+			t.Errorf("不应找到ClassUniversal的节点")
+		}
+		inner, ok := view.Lookup(ClassTag(ClassApplication, 100))
+		if ok == false || inner.Class() != ClassApplication {
+			t.Fatalf("没有找到inner")
+		}
+		if stringValue, ok := inner.Lookup(0); ok == false || string(stringValue.Value()) != "zhoujunhua" {
+			t.Errorf("stringValue = %v", stringValue)
+		}
+
+		count := 0
+		iterator := view.Children()
+		for child, ok := iterator.Next(); ok; child, ok = iterator.Next() {
+			if child.Tag() != count {
+				t.Errorf("child.Tag() = %d, count = %d", child.Tag(), count)
+			}
+			count++
+		}
+		if count != 101 || iterator.Err() != nil {
+			t.Errorf("count = %d, err = %v", count, iterator.Err())
+		}
+	}
+}
+
+// 测试视图读取字段时不分配内存
+func TestViewAllocs(t *testing.T) {
+	tlvBytes := buildWideObject(100).Bytes()
+	allocs := testing.AllocsPerRun(100, func() {
+		view, _ := NewView(tlvBytes)
+		inner, _ := view.Lookup(ClassTag(ClassApplication, 100))
+		inner.Lookup(0)
+		iterator := view.Children()
+		for _, ok := iterator.Next(); ok; _, ok = iterator.Next() {
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("allocs = %v", allocs)
+	}
+}
+
+// 测试子节点数据有误时迭代器返回错误
+func TestViewError(t *testing.T) {
+	view, err := NewView([]byte{0x21, 0x05, 0x00, 0x01, 0x01, 0x01, 0x7f})
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+
+	iterator := view.Children()
+	if _, ok := iterator.Next(); ok == false {
+		t.Fatalf("没有找到第一个子节点")
+	}
+	if _, ok := iterator.Next(); ok {
+		t.Errorf("第二个子节点不完整")
+	}
+
+	var tlvErr *Error
+	if errors.As(iterator.Err(), &tlvErr) == false || errors.Is(tlvErr, ErrTruncated) == false || tlvErr.Offset != 3 {
+		t.Errorf("err = %v", iterator.Err())
+	}
+
+	if _, err := NewView([]byte{0x01, 0x05}); errors.Is(err, ErrTruncated) == false {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func BenchmarkFromBytesGet(b *testing.B) {
+	tlvBytes := buildWideObject(500).Bytes()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		tlvObject := TLVObject{}
+		tlvObject.FromBytes(tlvBytes)
+		findObject, _ := tlvObject.Get(1)
+		findObject.GetInt32(250)
+	}
+}
+
+func BenchmarkViewLookup(b *testing.B) {
+	tlvBytes := buildWideObject(500).Bytes()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		view, _ := NewView(tlvBytes)
+		view.Lookup(250)
+	}
+}